@@ -13,16 +13,19 @@ func MatToTcl(mat *Mat) chirp.T {
 	case 0:
 		return chirp.MkList(nil)
 	case 1:
+		if s, ok := GetString(mat); ok && mat.Len() > 0 {
+			return chirp.MkString(s)
+		}
 		var vec []chirp.T
 		for i := 0; i < mat.S[0]; i++ {
-			vec = append(vec, ValToTcl(mat.M[i]))
+			vec = append(vec, ValToTcl(mat.Vals()[i]))
 		}
 		return chirp.MkList(vec)
 	default:
 		stride := Product(mat.S[1:])
 		var vec []chirp.T
 		for i := 0; i < mat.S[0]; i++ {
-			vec = append(vec, MatToTcl(&Mat{M: mat.M[i*stride : (i+1)*stride], S: mat.S[1:]}))
+			vec = append(vec, MatToTcl(&Mat{M: mat.Vals()[i*stride : (i+1)*stride], S: mat.S[1:]}))
 		}
 		return chirp.MkList(vec)
 	}
@@ -34,6 +37,8 @@ func ValToTcl(v Val) chirp.T {
 		case *ChirpBox:
 			return t.X
 	*/
+	case *Char:
+		return chirp.MkString(string(t.R))
 	case *Num:
 		Must(imag(t.F) == 0) // Until I figure out what to do with imaginary numbers.
 		return chirp.MkFloat(real(t.F))
@@ -48,19 +53,24 @@ func ValToTcl(v Val) chirp.T {
 
 func monadicTcl(c *Context, b Val, axis int) Val {
 	tcl := ValToTcl(b)
-	frame := c.Extra["chirp"].(*chirp.Frame)
-	z := frame.Eval(tcl)
+	z := chirpFrame(c).Eval(tcl)
 	return &Box{z}
 }
 
-func ChirpStringExtension(s string) Expression {
-	return &Literal{&Box{s}}
+// chirpFrame is the Context's chirp interpreter, made when first used.
+func chirpFrame(c *Context) *chirp.Frame {
+	frame, ok := c.Extra["chirp"].(*chirp.Frame)
+	if !ok {
+		frame = chirp.NewInterpreter()
+		c.Extra["chirp"] = frame
+	}
+	return frame
 }
 
-func Init(c *Context) {
-	c.StringExtension = ChirpStringExtension
-	c.Extra["chirp"] = chirp.NewInterpreter()
-	c.Monadics["tcl"] = monadicTcl
+// Register adds tcl to the builtins of a Registry.
+// Each Context gets its own chirp interpreter.
+func Register(r *Registry) {
+	r.RegisterMonadic("tcl", monadicTcl)
 }
 
 /*
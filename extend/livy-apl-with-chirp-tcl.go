@@ -6,12 +6,11 @@ import (
 	. "github.com/strickyak/livy-apl/lib"
 
 	"bytes"
-	"errors"
 	"flag"
 	"fmt"
 	"io"
-	"math"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"runtime/debug"
 	"strings"
@@ -24,29 +23,11 @@ import (
 )
 
 var Prompt = flag.String("prompt", "      ", "APL interpreter prompt")
+var Continuation = flag.String("continuation", "    > ", "APL interpreter prompt for continued lines")
 var Verbose = flag.Bool("v", false, "show debug messages on stderr")
 var CrashOnError = flag.Bool("e", false, "crash dump on error for debugging")
 var Raw = flag.Bool("raw", false, "print raw results for debugging")
 
-func EvalString(c *Context, line string) (val Val, err error) {
-	if !*CrashOnError {
-		defer func() {
-			r := recover()
-			if r != nil {
-				if *Verbose {
-					debug.PrintStack()
-				}
-				err = errors.New(fmt.Sprintf("%v", r))
-			}
-		}()
-	}
-	lex := Tokenize(line)
-	p := &Parser{c}
-	expr, _ := p.ParseSeq(lex, 0)
-	val = expr.Eval(c)
-	return
-}
-
 type SinkToNowhere struct{}
 
 func (SinkToNowhere) Write(bb []byte) (int, error) {
@@ -59,6 +40,38 @@ func main() {
 		Log.SetOutput(SinkToNowhere{})
 	}
 
+	r := NewRegistry()
+	extend.Register(r)
+	c := r.NewContext()
+	c.UseDefaultLimits()
+	in := &Interpreter{Context: c}
+	if *CrashOnError {
+		in.PanicHook = func(r interface{}) { panic(r) }
+	} else if *Verbose {
+		in.PanicHook = func(r interface{}) { debug.PrintStack() }
+	}
+
+	// Run script files named on the command line, instead of reading lines.
+	if flag.NArg() > 0 {
+		for _, filename := range flag.Args() {
+			err := c.RunScriptFile(filename, os.Stdout)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "****** %s: %s\n", filename, ReportError(err))
+				os.Exit(1)
+			}
+		}
+		return
+	}
+
+	// At the prompt, readline handles Control-C.  While evaluating, it stops the evaluation.
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	go func() {
+		for range interrupts {
+			in.Interrupt()
+		}
+	}()
+
 	home := os.Getenv("HOME")
 	if home == "" {
 		home = "."
@@ -77,25 +90,16 @@ func main() {
 	}
 	defer rl.Close()
 
-	c := &Context{
-		Globals:  make(map[string]Val),
-		Monadics: StandardMonadics,
-		Dyadics:  StandardDyadics,
-		Extra:    make(map[string]interface{}),
-	}
-	extend.Init(c)
-	c.Globals["Pi"] = &Num{math.Pi}
-	c.Globals["Tau"] = &Num{2.0 * math.Pi}
-	c.Globals["E"] = &Num{math.E}
-	c.Globals["Phi"] = &Num{math.Phi}
-
 	i := 0
+	pending := "" // Lines of an incomplete statement, awaiting more.
 	for {
 		line, err := rl.Readline()
 		if err == readline.ErrInterrupt {
-			if len(line) == 0 {
+			if len(line) == 0 && pending == "" {
 				break
 			} else {
+				pending = ""
+				rl.SetPrompt(*Prompt)
 				continue
 			}
 		} else if err == io.EOF {
@@ -103,18 +107,29 @@ func main() {
 		}
 
 		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
+		if pending == "" {
+			if line == "" {
+				continue
+			}
+
+			if strings.HasPrefix(line, ")") {
+				c.Command(line[1:])
+				continue
+			}
 		}
 
-		if strings.HasPrefix(line, ")") {
-			c.Command(line[1:])
+		source := pending + line
+		if IsIncomplete(source) {
+			pending = source + "\n"
+			rl.SetPrompt(*Continuation)
 			continue
 		}
+		pending = ""
+		rl.SetPrompt(*Prompt)
 
-		result, complaint := EvalString(c, line)
+		result, complaint := in.Eval(source)
 		if complaint != nil {
-			fmt.Fprintf(os.Stderr, "****** ERROR: %s\n", complaint)
+			fmt.Fprintf(os.Stderr, "****** %s\n", ReportError(complaint))
 			continue
 		}
 
@@ -132,7 +147,7 @@ func main() {
 				}
 				fmt.Fprintf(bb, "rho")
 			}
-			fmt.Fprintf(os.Stdout, "   %s = (%T) %s\n%s\n", name, result, bb.String(), result.Pretty())
+			fmt.Fprintf(os.Stdout, "   %s = (%T) %s\n%s\n", name, result, bb.String(), c.Pretty(result))
 		}
 		i++
 	}
@@ -26,7 +26,7 @@ func monadicCxSliceToCxSlice(c *Context, b Val, dim int, fn func([]complex128) [
 	}
 	vec := make([]complex128, m.S[0])
 	for i := 0; i < m.S[0]; i++ {
-		vec[i] = m.M[i].GetScalarCx()
+		vec[i] = m.Vals()[i].GetScalarCx()
 	}
 	out := fn(vec)
 	zz := make([]Val, len(out))
@@ -36,7 +36,8 @@ func monadicCxSliceToCxSlice(c *Context, b Val, dim int, fn func([]complex128) [
 	return &Mat{M: zz, S: []int{len(zz)}}
 }
 
-func init() {
-	StandardMonadics["fft"] = monadicFFT
-	StandardMonadics["ifft"] = monadicIFFT
+// Register adds fft and ifft to the builtins of a Registry.
+func Register(r *Registry) {
+	r.RegisterMonadic("fft", monadicFFT)
+	r.RegisterMonadic("ifft", monadicIFFT)
 }
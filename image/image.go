@@ -31,13 +31,14 @@ func loadImageToVal(filename string) Val {
 			vec = append(vec, FloatNum(float64(_r)/0xFFFF), FloatNum(float64(_b)/0xFFFF), FloatNum(float64(_g)/0xFFFF), FloatNum(float64(_a)/0xFFFF))
 		}
 	}
-	return &Mat{vec, []int{mx, my, 4}}
+	return &Mat{M: vec, S: []int{mx, my, 4}}
 }
 
 func monadicImage(c *Context, b Val, dim int) Val {
 	return loadImageToVal("/tmp/image")
 }
 
-func init() {
-	StandardMonadics["image"] = monadicImage
+// Register adds image to the builtins of a Registry.
+func Register(r *Registry) {
+	r.RegisterMonadic("image", monadicImage)
 }
@@ -0,0 +1,88 @@
+package livy
+
+// Conformability of the arguments of the scalar dyadic functions and each, as in APL:
+// arguments of the same shape pair their elements in order,
+// and a scalar or an array with one element pairs with every element of the other
+// (scalar and singleton extension).  Otherwise, arguments of different ranks
+// are a RANK ERROR, and of the same rank but different shapes, a LENGTH ERROR.
+
+// Conformed holds the elements of two conforming arguments.
+type Conformed struct {
+	Shape []int // Shape of the result, or nil for a scalar.
+	A, B  []Val // Elements of the arguments.  A side with one element is extended.
+}
+
+// Conform checks that the arguments of a function conform, and pairs their elements.
+// The name of the function, if not empty, begins the error message.
+func Conform(name string, a, b Val) *Conformed {
+	shape, _ := conformShape(name, a, b)
+	_, aVec := shapeAndVals(a)
+	_, bVec := shapeAndVals(b)
+	return &Conformed{Shape: shape, A: aVec, B: bVec}
+}
+
+// conformShape checks that the arguments conform, without looking at their elements,
+// and returns the shape and size of the result.
+func conformShape(name string, a, b Val) ([]int, int) {
+	aShape, aSize := a.Shape(), a.Size()
+	bShape, bSize := b.Shape(), b.Size()
+	switch {
+	case sameInts(aShape, bShape):
+		return aShape, aSize
+	case aSize == 1 && bSize == 1:
+		if len(bShape) > len(aShape) {
+			return bShape, 1 // Keep the greater rank.
+		}
+		return aShape, 1
+	case aSize == 1:
+		return bShape, bSize
+	case bSize == 1:
+		return aShape, aSize
+	}
+	kind := LengthError
+	if len(aShape) != len(bShape) {
+		kind = RankError
+	}
+	if name != "" {
+		name += ": "
+	}
+	Panicf(kind, "%sLHS shape %v does not conform to RHS shape %v", name, aShape, bShape)
+	panic(0)
+}
+
+// Len is the number of pairs of elements.
+func (o *Conformed) Len() int {
+	if len(o.A) == 1 {
+		return len(o.B)
+	}
+	return len(o.A)
+}
+
+// Pair returns the i-th pair of elements.
+func (o *Conformed) Pair(i int) (Val, Val) {
+	a, b := o.A[0], o.B[0]
+	if len(o.A) > 1 {
+		a = o.A[i]
+	}
+	if len(o.B) > 1 {
+		b = o.B[i]
+	}
+	return a, b
+}
+
+// Result makes the result from the values for each pair.
+func (o *Conformed) Result(vec []Val) Val {
+	return matOrScalar(o.Shape, vec)
+}
+
+func sameInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -1,11 +1,14 @@
 package livy
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"math"
+	"math/rand"
 	"os"
 	"sort"
+	"strings"
 )
 
 var Log *log.Logger = log.New(os.Stderr, "livy: ", 0)
@@ -13,10 +16,11 @@ var Log *log.Logger = log.New(os.Stderr, "livy: ", 0)
 type StringExtensionFunc func(s string) Expression
 
 type Context struct {
-	Globals    map[string]Val
-	Monadics   map[string]MonadicFunc
-	Dyadics    map[string]DyadicFunc
-	LocalStack []map[string]Val
+	Globals  map[string]Val
+	Monadics map[string]MonadicFunc // This Context's own table, with builtins and defs.
+	Dyadics  map[string]DyadicFunc  // This Context's own table, with builtins and defs.
+	Registry *Registry              // The builtins it was made with, restored when defs are deleted.
+	Frames   []*Frame               // Calls of user-defined functions in progress.
 
 	FormatReal         string
 	FormatImagPlus     string
@@ -24,37 +28,196 @@ type Context struct {
 	FormatComplexPlus  string
 	FormatComplexMinus string
 
+	// Relative tolerance of comparisons and integer conversions, or 0 for exact.
+	CompareTolerance float64
+	// Significant digits for printing numbers, or 0 for as many as needed.
+	PrintPrecision int
+	// Seed for random numbers, and the generator started from it.
+	Seed int64
+	Rand *rand.Rand
+	// Goroutines for scalar functions and products of large arrays,
+	// or 0 for one per CPU.  1 does everything in the calling goroutine.
+	Workers int
+	// Evaluation stops with an INTERRUPT ERROR when GoContext is done,
+	// or after MaxSteps steps (loop iterations and calls of user functions), if not 0.
+	GoContext   context.Context
+	MaxSteps    int64
+	steps       int64 // Steps of the current evaluation, updated atomically.
+	interrupted int32 // Set atomically by Interrupt.
+	// Nested calls of user functions are a WS FULL ERROR past MaxDepth, if not 0.
+	MaxDepth int
+	// An array of more than MaxArraySize elements, or more than MaxElements elements
+	// made in all by one evaluation, is a WS FULL ERROR.  0 means no limit.
+	MaxArraySize int
+	MaxElements  int64
+	elements     int64 // Elements made by the current evaluation, updated atomically.
+
 	StringExtension StringExtensionFunc
 	Extra           map[string]interface{}
+
+	// Source text of user definitions, by name, for saving workspaces.
+	MonadicDefs   map[string]string
+	DyadicDefs    map[string]string
+	WorkspaceName string
+	// Function assignments whose lambdas see the locals of a call,
+	// which their source alone cannot restore.
+	capturedDefs map[defKey]bool
+
+	// User-defined operators taking function operands.
+	Operators map[string]*UserOperator
 }
 
+// NewContext makes a Context with its own copy of the standard functions.
+// Use Registry.NewContext for more builtins.
 func NewContext() *Context {
+	return NewRegistry().NewContext()
+}
+
+func newContext() *Context {
 	c := &Context{
 		Globals:            make(map[string]Val),
-		Monadics:           StandardMonadics,
-		Dyadics:            StandardDyadics,
 		Extra:              make(map[string]interface{}),
+		MonadicDefs:        make(map[string]string),
+		DyadicDefs:         make(map[string]string),
+		Operators:          make(map[string]*UserOperator),
 		FormatReal:         "%g",
 		FormatImagPlus:     "+j%g",
 		FormatImagMinus:    "-j%g",
-		FormatComplexPlus:  "%g-j%g",
-		FormatComplexMinus: "%g+j%g",
+		FormatComplexPlus:  "%g+j%g",
+		FormatComplexMinus: "%g-j%g",
+		CompareTolerance:   DefaultCompareTolerance,
+		Seed:               DefaultSeed,
+		MaxDepth:           DefaultMaxDepth,
 	}
+	c.initGlobals()
+	return c
+}
+
+func (c *Context) initGlobals() {
 	c.Globals["Pi"] = &Num{math.Pi}
 	c.Globals["Tau"] = &Num{2.0 * math.Pi}
 	c.Globals["E"] = &Num{math.E}
 	c.Globals["Phi"] = &Num{math.Phi}
 	c.Globals["J"] = &Num{complex(0, 1)}
-	return c
+}
+
+// defKey names a monadic or dyadic user definition.
+type defKey struct {
+	dyadic bool
+	name   string
+}
+
+// defsOf is the table of sources of monadic or dyadic user definitions.
+func (c *Context) defsOf(dyadic bool) map[string]string {
+	if dyadic {
+		return c.DyadicDefs
+	}
+	return c.MonadicDefs
+}
+
+// RememberDef records the source of a user definition,
+// so it can be written by SaveWorkspace.
+func (c *Context) RememberDef(dyadic bool, name string, source string) {
+	delete(c.capturedDefs, defKey{dyadic, name})
+	if dyadic {
+		if c.DyadicDefs == nil {
+			c.DyadicDefs = make(map[string]string)
+		}
+		c.DyadicDefs[name] = source
+	} else {
+		if c.MonadicDefs == nil {
+			c.MonadicDefs = make(map[string]string)
+		}
+		c.MonadicDefs[name] = source
+	}
+}
+
+// markCaptured records that a user definition captured the locals of a call,
+// so SaveWorkspace refuses to save it, until it is defined again.
+func (c *Context) markCaptured(dyadic bool, name string) {
+	if c.capturedDefs == nil {
+		c.capturedDefs = make(map[defKey]bool)
+	}
+	c.capturedDefs[defKey{dyadic, name}] = true
+}
+
+// Clear forgets all variables and user definitions, and resets $CT, $PP, and $RL,
+// leaving the Context as if it came from NewContext.
+// Settings of the embedding program, like Workers and the limits, are kept.
+func (c *Context) Clear() {
+	for name := range c.MonadicDefs {
+		c.unsetMonadic(name)
+	}
+	for name := range c.DyadicDefs {
+		c.unsetDyadic(name)
+	}
+	c.MonadicDefs = make(map[string]string)
+	c.DyadicDefs = make(map[string]string)
+	c.Operators = make(map[string]*UserOperator)
+	c.capturedDefs = nil
+	c.Globals = make(map[string]Val)
+	c.Frames = nil
+	c.WorkspaceName = ""
+	c.CompareTolerance = DefaultCompareTolerance
+	c.PrintPrecision = 0
+	c.Reseed(DefaultSeed)
+	c.initGlobals()
 }
 
 func (c *Context) Command(s string) {
-	if s == "" {
+	if strings.TrimSpace(s) == "" {
 		s = "?"
 	}
 
-	switch s[0] {
-	case 'v':
+	words := strings.Fields(s)
+	switch {
+	case words[0] == "save":
+		name := c.WorkspaceName
+		if len(words) > 1 {
+			name = words[1]
+		}
+		if name == "" {
+			fmt.Fprintf(os.Stderr, "Usage: )save NAME\n")
+			return
+		}
+		err := c.SaveWorkspaceFile(name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "****** ERROR: %s\n", err)
+			return
+		}
+		fmt.Fprintf(os.Stderr, "Saved %s\n", WorkspaceFilename(name))
+	case words[0] == "load":
+		if len(words) != 2 {
+			fmt.Fprintf(os.Stderr, "Usage: )load NAME\n")
+			return
+		}
+		err := c.LoadWorkspaceFile(words[1])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "****** ERROR: %s\n", err)
+			return
+		}
+		fmt.Fprintf(os.Stderr, "Loaded %s\n", WorkspaceFilename(words[1]))
+	case words[0] == "copy":
+		if len(words) < 2 {
+			fmt.Fprintf(os.Stderr, "Usage: )copy NAME [Var_or_def...]\n")
+			return
+		}
+		err := c.CopyWorkspaceFile(words[1], words[2:])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "****** ERROR: %s\n", err)
+			return
+		}
+	case words[0] == "run":
+		if len(words) != 2 {
+			fmt.Fprintf(os.Stderr, "Usage: )run FILE\n")
+			return
+		}
+		err := c.RunScriptFile(words[1], os.Stdout)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "****** %s\n", ReportError(err))
+			return
+		}
+	case s[0] == 'v':
 		var names []string
 		maxLen := 0
 		for k, _ := range c.Globals {
@@ -71,7 +234,7 @@ func (c *Context) Command(s string) {
 			format := fmt.Sprintf("%%%ds : %%s\n", maxLen)
 			fmt.Fprintf(os.Stderr, format, k, c.Globals[k])
 		}
-	case 'm':
+	case s[0] == 'm':
 		var names []string
 		for k, _ := range c.Monadics {
 			names = append(names, k)
@@ -81,7 +244,7 @@ func (c *Context) Command(s string) {
 			fmt.Fprintf(os.Stderr, "%s ", k)
 		}
 		fmt.Fprintf(os.Stderr, "\n")
-	case 'd':
+	case s[0] == 'd':
 		var names []string
 		for k, _ := range c.Dyadics {
 			names = append(names, k)
@@ -95,6 +258,8 @@ func (c *Context) Command(s string) {
 		fmt.Fprintf(os.Stderr, `Unknown command.
 
 Commands:  )v[ars]  )m[onadics]  )d[yadics]
+           )save [NAME]  )load NAME  )copy NAME [Var_or_def...]
+           )run FILE
 
 `)
 		return
@@ -0,0 +1,261 @@
+package livy
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// Conversions between Go values and livy values, for embedding.
+// Strings are char vectors, and a vector of strings is a vector of boxed char vectors.
+
+// ToVal converts a Go value to a livy value.
+// It takes a Val as it is, numbers, bools, strings, *big.Int and *big.Rat (as exact numbers),
+// and slices of numbers or strings.  Integers too large for a float64 become exact.
+func ToVal(x interface{}) (Val, error) {
+	switch t := x.(type) {
+	case Val:
+		return t, nil
+	case float64:
+		return FloatNum(t), nil
+	case int:
+		return intVal(int64(t)), nil
+	case int64:
+		return intVal(t), nil
+	case complex128:
+		return CxNum(t), nil
+	case bool:
+		return BoolNum(t), nil
+	case string:
+		return StringMat(t), nil
+	case *big.Int:
+		if t != nil {
+			return ExactBig(t), nil
+		}
+	case *big.Rat:
+		if t != nil {
+			return ExactRat(new(big.Rat).Set(t)), nil
+		}
+	case []float64:
+		return FloatsMat(t), nil
+	case []int:
+		xs := make([]complex128, len(t))
+		for i, e := range t {
+			if e < -maxExactFloatInt || e > maxExactFloatInt {
+				vals := make([]Val, len(t))
+				for i, e := range t {
+					vals[i] = intVal(int64(e))
+				}
+				return &Mat{M: vals, S: []int{len(vals)}}, nil
+			}
+			xs[i] = complex(float64(e), 0)
+		}
+		return PackedMat(PackCxs(xs), []int{len(xs)}), nil
+	case [][]float64:
+		mat, err := FloatMatrixMat(t)
+		if err != nil {
+			return nil, err
+		}
+		return mat, nil
+	case []complex128:
+		return CxsMat(t), nil
+	case []string:
+		return StringsMat(t), nil
+	}
+	return nil, convertErrorf(DomainError, "cannot convert Go %T to a livy value", x)
+}
+
+// maxExactFloatInt is the largest integer below which every integer is a float64.
+const maxExactFloatInt = 1 << 53
+
+// intVal is a number for an integer, exact if a float64 cannot hold it.
+func intVal(i int64) Val {
+	if i < -maxExactFloatInt || i > maxExactFloatInt {
+		return ExactInt(i)
+	}
+	return IntNum(int(i))
+}
+
+// FromVal converts a livy value to the most natural Go value:
+// a float64 or complex128 for a number, a *big.Rat for an exact number,
+// a string for a char or char vector, a []float64 or []complex128 for a numeric vector,
+// a [][]float64 for a real matrix, or a []string for a vector of strings.
+// An empty vector is a []float64.  Anything else is returned as the Val itself.
+func FromVal(v Val) interface{} {
+	switch t := v.(type) {
+	case *Char:
+		return string(t.R)
+	case *Exact:
+		return t.Rat()
+	case Exact:
+		return t.Rat()
+	case *Num:
+		if imag(t.F) == 0 {
+			return real(t.F)
+		}
+		return t.F
+	case *Mat:
+		if s, ok := GetString(t); ok && t.Len() > 0 {
+			return s
+		}
+		switch len(t.S) {
+		case 1:
+			if xs, err := GetFloats(t); err == nil {
+				return xs
+			}
+			if xs, err := GetCxs(t); err == nil {
+				return xs
+			}
+			if ss, err := GetStrings(t); err == nil {
+				return ss
+			}
+		case 2:
+			if xss, err := GetFloatMatrix(t); err == nil {
+				return xss
+			}
+		}
+	}
+	return v
+}
+
+// FloatsMat makes a numeric vector.
+func FloatsMat(xs []float64) *Mat {
+	cxs := make([]complex128, len(xs))
+	for i, x := range xs {
+		cxs[i] = complex(x, 0)
+	}
+	return PackedMat(PackCxs(cxs), []int{len(cxs)})
+}
+
+// FloatMatrixMat makes a numeric matrix with a row for each slice.
+// The rows must all have the same length.
+func FloatMatrixMat(xss [][]float64) (*Mat, error) {
+	rows, cols := len(xss), 0
+	if rows > 0 {
+		cols = len(xss[0])
+	}
+	cxs := make([]complex128, 0, rows*cols)
+	for i, xs := range xss {
+		if len(xs) != cols {
+			return nil, convertErrorf(LengthError, "row %d has length %d, but row 0 has %d", i, len(xs), cols)
+		}
+		for _, x := range xs {
+			cxs = append(cxs, complex(x, 0))
+		}
+	}
+	return PackedMat(PackCxs(cxs), []int{rows, cols}), nil
+}
+
+// CxsMat makes a numeric vector of complex numbers.
+func CxsMat(xs []complex128) *Mat {
+	cxs := make([]complex128, len(xs))
+	copy(cxs, xs)
+	return PackedMat(PackCxs(cxs), []int{len(cxs)})
+}
+
+// StringsMat makes a vector of boxed char vectors.
+func StringsMat(ss []string) *Mat {
+	vec := make([]Val, len(ss))
+	for i, s := range ss {
+		vec[i] = &Box{StringMat(s)}
+	}
+	return &Mat{M: vec, S: []int{len(vec)}}
+}
+
+// GetFloats returns the real numbers of a scalar or vector.
+func GetFloats(v Val) ([]float64, error) {
+	cxs, err := getNumbers(v, 1)
+	if err != nil {
+		return nil, err
+	}
+	return realsOf(cxs)
+}
+
+// GetFloatMatrix returns the real numbers of a matrix, by rows.
+func GetFloatMatrix(v Val) ([][]float64, error) {
+	cxs, err := getNumbers(v, 2)
+	if err != nil {
+		return nil, err
+	}
+	if len(v.Shape()) != 2 {
+		return nil, convertErrorf(RankError, "not a matrix: shape %v", v.Shape())
+	}
+	xs, err := realsOf(cxs)
+	if err != nil {
+		return nil, err
+	}
+	rows, cols := v.Shape()[0], v.Shape()[1]
+	xss := make([][]float64, rows)
+	for i := range xss {
+		xss[i] = xs[i*cols : (i+1)*cols : (i+1)*cols]
+	}
+	return xss, nil
+}
+
+// GetCxs returns the numbers of a scalar or vector.
+func GetCxs(v Val) ([]complex128, error) {
+	return getNumbers(v, 1)
+}
+
+// GetStrings returns the strings of a scalar or vector,
+// each element being a boxed char vector or string, or a single char.
+func GetStrings(v Val) ([]string, error) {
+	if r := len(v.Shape()); r > 1 {
+		return nil, convertErrorf(RankError, "rank %d is not a vector", r)
+	}
+	var ss []string
+	for i, e := range v.Ravel() {
+		s, ok := GetString(e)
+		if box, isBox := e.(*Box); isBox {
+			switch x := box.X.(type) {
+			case string:
+				s, ok = x, true
+			case Val:
+				s, ok = GetString(x)
+			}
+		}
+		if !ok {
+			return nil, convertErrorf(DomainError, "element %d is not a string: %s", i, e)
+		}
+		ss = append(ss, s)
+	}
+	return ss, nil
+}
+
+// getNumbers returns the numbers of a value of at most the rank.
+func getNumbers(v Val, rank int) ([]complex128, error) {
+	if r := len(v.Shape()); r > rank {
+		return nil, convertErrorf(RankError, "rank %d is more than %d", r, rank)
+	}
+	if p := packedOf(v); p != nil {
+		cxs := make([]complex128, p.Len())
+		for i := range cxs {
+			cxs[i] = p.Cx(i)
+		}
+		return cxs, nil
+	}
+	var cxs []complex128
+	for i, e := range v.Ravel() {
+		switch e.(type) {
+		case *Num, Num, *Exact, Exact:
+			cxs = append(cxs, e.GetScalarCx())
+		default:
+			return nil, convertErrorf(DomainError, "element %d is not a number: %s", i, e)
+		}
+	}
+	return cxs, nil
+}
+
+func realsOf(cxs []complex128) ([]float64, error) {
+	xs := make([]float64, len(cxs))
+	for i, x := range cxs {
+		if imag(x) != 0 {
+			return nil, convertErrorf(DomainError, "element %d is not real: %s", i, Cx2Str(x))
+		}
+		xs[i] = real(x)
+	}
+	return xs, nil
+}
+
+func convertErrorf(kind ErrorKind, format string, args ...interface{}) *LivyError {
+	return &LivyError{Kind: kind, Msg: fmt.Sprintf(format, args...), Pos: -1}
+}
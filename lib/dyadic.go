@@ -10,9 +10,11 @@ type DyadicFunc func(c *Context, a Val, b Val, axis int) Val
 
 var StandardDyadics = map[string]DyadicFunc{
 	"member": dyadicMember,
+	"iota":   dyadicIota,
+	"i":      dyadicIota,
 	"e":      dyadicMember,
-	"j":      WrapMatMatDyadic(WrapCxDyadic(cxcxJ)),
-	"rect":   WrapMatMatDyadic(WrapCxDyadic(cxcxRect)),
+	"j":      WrapMatCxDyadic(cxcxJ),
+	"rect":   WrapMatCxDyadic(cxcxRect),
 
 	"rho": dyadicRho,
 	"p":   dyadicRho,
@@ -25,55 +27,52 @@ var StandardDyadics = map[string]DyadicFunc{
 	"drop":      dyadicDrop,
 	"compress":  dyadicCompress,
 	"expand":    dyadicExpand,
+	"format":    dyadicFormat,
+	"encode":    dyadicEncode,
+	"decode":    dyadicDecode,
+	"deal":      dyadicDeal,
   // `/`:         dyadicCompress,
 	`\`:         dyadicExpand,
 
-	"==": WrapMatMatDyadic(WrapCxBoolDyadic(
-		func(a, b complex128) bool { return a == b })),
-	"!=": WrapMatMatDyadic(WrapCxBoolDyadic(
-		func(a, b complex128) bool { return a != b })),
-	"<": WrapMatMatDyadic(WrapFloatBoolDyadic(
-		func(a, b float64) bool { return a < b })),
-	">": WrapMatMatDyadic(WrapFloatBoolDyadic(
-		func(a, b float64) bool { return a > b })),
-	"<=": WrapMatMatDyadic(WrapFloatBoolDyadic(
-		func(a, b float64) bool { return a <= b })),
-	">=": WrapMatMatDyadic(WrapFloatBoolDyadic(
-		func(a, b float64) bool { return a >= b })),
-	"and": WrapMatMatDyadic(WrapFloatBoolDyadic(ffand)),
-	"or":  WrapMatMatDyadic(WrapFloatBoolDyadic(ffor)),
-	"xor": WrapMatMatDyadic(WrapFloatBoolDyadic(ffxor)),
-
-	"+": WrapMatMatDyadic(WrapCxDyadic(
-		func(a, b complex128) complex128 { return a + b })),
-	"-": WrapMatMatDyadic(WrapCxDyadic(
-		func(a, b complex128) complex128 { return a - b })),
-	"*": WrapMatMatDyadic(WrapCxDyadic(
-		func(a, b complex128) complex128 { return a * b })),
-	"/": WrapMatMatDyadic(WrapCxDyadic(
-		func(a, b complex128) complex128 { return a / b })),
-	"div": WrapMatMatDyadic(WrapCxDyadic(
-		func(a, b complex128) complex128 { return a / b })),
-	"**": WrapMatMatDyadic(WrapCxDyadic(
-		func(a, b complex128) complex128 { return cmplx.Pow(a, b) })),
-	"remainder": WrapMatMatDyadic(WrapFloatDyadic(
-		func(a, b float64) float64 { return math.Remainder(a, b) })),
-	"mod": WrapMatMatDyadic(WrapFloatDyadic(
-		func(a, b float64) float64 { return math.Mod(a, b) })),
-	"atan": WrapMatMatDyadic(WrapFloatDyadic(
-		func(a, b float64) float64 { return math.Atan2(a, b) })),
-	"copysign": WrapMatMatDyadic(WrapFloatDyadic(
-		func(a, b float64) float64 { return math.Copysign(a, b) })),
-	"dim": WrapMatMatDyadic(WrapFloatDyadic(
-		func(a, b float64) float64 { return math.Dim(a, b) })),
-	"hypot": WrapMatMatDyadic(WrapFloatDyadic(
-		func(a, b float64) float64 { return math.Hypot(a, b) })),
-	"isInf": WrapMatMatDyadic(WrapFloatBoolDyadic(
-		func(a, b float64) bool { return math.IsInf(a, int(b)) })),
-	"jn": WrapMatMatDyadic(WrapFloatDyadic(
-		func(a, b float64) float64 { return math.Jn(int(a), b) })),
-	"yn": WrapMatMatDyadic(WrapFloatDyadic(
-		func(a, b float64) float64 { return math.Yn(int(a), b) })),
+	"==": WrapMatEqualDyadic(false),
+	"!=": WrapMatEqualDyadic(true),
+	"<": WrapMatCompareDyadic(
+		func(cmp int) bool { return cmp < 0 }),
+	">": WrapMatCompareDyadic(
+		func(cmp int) bool { return cmp > 0 }),
+	"<=": WrapMatCompareDyadic(
+		func(cmp int) bool { return cmp <= 0 }),
+	">=": WrapMatCompareDyadic(
+		func(cmp int) bool { return cmp >= 0 }),
+	"and": WrapMatFloatBoolDyadic(ffand),
+	"or":  WrapMatFloatBoolDyadic(ffor),
+	"xor": WrapMatFloatBoolDyadic(ffxor),
+
+	"+":   WrapMatExactCxDyadic(exactAdd, cxAdd),
+	"-":   WrapMatExactCxDyadic(exactSub, cxSub),
+	"*":   WrapMatExactCxDyadic(exactMul, cxMul),
+	"/":   WrapMatExactCxDyadic(exactDiv, cxDiv),
+	"div": WrapMatExactCxDyadic(exactDiv, cxDiv),
+	"**": WrapMatExactCxDyadic(exactPow,
+		func(a, b complex128) complex128 { return cmplx.Pow(a, b) }),
+	"remainder": WrapMatFloatDyadic(
+		func(a, b float64) float64 { return math.Remainder(a, b) }),
+	"mod": WrapMatExactFloatDyadic(exactMod,
+		func(a, b float64) float64 { return math.Mod(a, b) }),
+	"atan": WrapMatFloatDyadic(
+		func(a, b float64) float64 { return math.Atan2(a, b) }),
+	"copysign": WrapMatFloatDyadic(
+		func(a, b float64) float64 { return math.Copysign(a, b) }),
+	"dim": WrapMatFloatDyadic(
+		func(a, b float64) float64 { return math.Dim(a, b) }),
+	"hypot": WrapMatFloatDyadic(
+		func(a, b float64) float64 { return math.Hypot(a, b) }),
+	"isInf": WrapMatFloatBoolDyadic(
+		func(a, b float64) bool { return math.IsInf(a, int(b)) }),
+	"jn": WrapMatFloatDyadic(
+		func(a, b float64) float64 { return math.Jn(int(a), b) }),
+	"yn": WrapMatFloatDyadic(
+		func(a, b float64) float64 { return math.Yn(int(a), b) }),
 }
 
 var Zero = &Num{0.0}
@@ -100,7 +99,7 @@ var IdentityValueOfDyadic = map[string]Val{
 // Mod forcing positive result, since I don't actually know what Go does.
 func Mod(x int, modulus int) int {
 	if modulus < 1 {
-		Log.Panicf("Nonpositive modulus: %d", modulus)
+		Panicf(DomainError, "Nonpositive modulus: %d", modulus)
 	}
 	return ((x % modulus) + modulus) % modulus
 }
@@ -125,59 +124,77 @@ func ffxor(a, b float64) bool {
 	return y
 }
 
+// MkOuterProduct applies fn to every pair of an element of the LHS and an element of the RHS.
+// The arguments may have any rank, or be scalars, and the result has shape `(rho A) , rho B`.
 func MkOuterProduct(name string, fn DyadicFunc) DyadicFunc {
+	return mkOuterProduct(name, fn, false)
+}
+
+// mkOuterProduct makes an outer product that runs in parallel for large arrays of scalars,
+// if parallel says that fn may be called from many goroutines at once.
+func mkOuterProduct(name string, fn DyadicFunc, parallel bool) DyadicFunc {
 	return func(c *Context, a Val, b Val, axis int) Val {
-		ma, ok := a.(*Mat)
-		if !ok {
-			Log.Panicf("outer product: LHS not a matrix (%T)", a)
-		}
-		mb, ok := b.(*Mat)
-		if !ok {
-			Log.Panicf("outer product: RHS not a matrix (%T)", b)
-		}
-		if len(ma.S) != 1 {
-			Log.Panicf("outer product: LHS not a vector (rank %d)", len(ma.S))
-		}
-		if len(mb.S) != 1 {
-			Log.Panicf("outer product: RHS not a vector (rank %d)", len(mb.S))
-		}
-		aa := ma.M
-		bb := mb.M
-		sz := len(aa) * len(bb)
-		vec := make([]Val, sz)
-		for ia, fa := range aa {
-			for ib, fb := range bb {
-				x := fn(c, fa, fb, -1)
-				vec[ia*len(bb)+ib] = x
+		aShape, aa := shapeAndVals(a)
+		bShape, bb := shapeAndVals(b)
+
+		var shape []int
+		shape = append(shape, aShape...)
+		shape = append(shape, bShape...)
+
+		vec := make([]Val, c.AllocShape(shape))
+		each := func(lo, hi int) {
+			for i := lo; i < hi; i++ {
+				vec[i] = fn(c, aa[i/len(bb)], bb[i%len(bb)], -1)
 			}
 		}
-		return &Mat{M: vec, S: []int{len(aa), len(bb)}}
+		if parallel && allScalars(aa) && allScalars(bb) {
+			c.parallelFor(len(vec), each)
+		} else {
+			each(0, len(vec))
+		}
+		return matOrScalar(shape, vec)
+	}
+}
+
+// withPackedInnerProduct adds a fast path for packed numeric arrays to an inner product.
+func withPackedInnerProduct(general DyadicFunc, kernel1, kernel2 FuncCxCxCx) DyadicFunc {
+	return func(c *Context, a, b Val, axis int) Val {
+		if z := packedInnerProduct(c, a, b, kernel1, kernel2); z != nil {
+			return z
+		}
+		return general(c, a, b, axis)
 	}
 }
 
 func MkInnerProduct(name string, fn1, fn2 DyadicFunc) DyadicFunc {
+	return mkInnerProduct(name, fn1, fn2, false)
+}
+
+// mkInnerProduct makes an inner product that runs in parallel for large arrays of scalars,
+// if parallel says that fn1 and fn2 may be called from many goroutines at once.
+func mkInnerProduct(name string, fn1, fn2 DyadicFunc, parallel bool) DyadicFunc {
 	return func(c *Context, a Val, b Val, axis int) Val {
 		mat1, ok := a.(*Mat)
 		if !ok {
-			Log.Panicf("LHS of inner product %q not a matrix: %v", name, a)
+			Panicf(DomainError, "LHS of inner product %q not a matrix: %v", name, a)
 		}
 
 		mat2, ok := b.(*Mat)
 		if !ok {
-			Log.Panicf("RHS of inner product %q not a matrix: %v", name, b)
+			Panicf(DomainError, "RHS of inner product %q not a matrix: %v", name, b)
 		}
 
-		vec1, vec2 := mat1.M, mat2.M
+		vec1, vec2 := mat1.Vals(), mat2.Vals()
 		shape1, shape2 := mat1.S, mat2.S
 		rank1, rank2 := len(shape1), len(shape2)
 		if rank1 < 1 {
-			Log.Panicf("LHS of inner product %q has rank 0: %v", name, a)
+			Panicf(RankError, "LHS of inner product %q has rank 0: %v", name, a)
 		}
 		if rank2 < 1 {
-			Log.Panicf("RHS of inner product %q has rank 0: %v", name, b)
+			Panicf(RankError, "RHS of inner product %q has rank 0: %v", name, b)
 		}
 		if shape1[rank1-1] != shape2[0] {
-			Log.Panicf("Dimension conflict in inner product %q: LHS is shape %v; RHS is shape %v", name, shape1, shape2)
+			Panicf(LengthError, "Dimension conflict in inner product %q: LHS is shape %v; RHS is shape %v", name, shape1, shape2)
 		}
 
 		var outShape []int
@@ -187,39 +204,28 @@ func MkInnerProduct(name string, fn1, fn2 DyadicFunc) DyadicFunc {
 		for _, sz := range shape2[1:] {
 			outShape = append(outShape, sz)
 		}
-		outVec := make([]Val, Product(outShape))
-		innerStride1 := 1
-		innerStride2 := Product(shape2[1:])
-		innerLength := shape2[0]
-		Log.Printf("innerStride 1:%d 2:%d innerLength:%d", innerStride1, innerStride2, innerLength)
-
-		var recurse func(shape1, shape2 []int, off1, off2 int, outShape []int, outOff int)
-		recurse = func(shape1, shape2 []int, off1, off2 int, outShape []int, outOff int) {
-			rank1, rank2, outRank := len(shape1), len(shape2), len(outShape)
-			Log.Printf("Rank(%d,%d -> %d) : shape( %v , %v -> %v ) : off (%d,%d -> %d)", rank1, rank2, outRank, shape1, shape2, outShape, off1, off2, outOff)
-			if outRank == 0 {
+		outVec := make([]Val, c.AllocShape(outShape))
+		// Each row of the LHS meets each column of the RHS.
+		innerLength, cols := shape2[0], Product(shape2[1:])
+		Log.Printf("innerLength:%d cols:%d", innerLength, cols)
+
+		each := func(lo, hi int) {
+			for out := lo; out < hi; out++ {
+				off1, off2 := (out/cols)*innerLength, out%cols
 				j := innerLength - 1
-				rhs := fn2(c, vec1[off1+j*innerStride1], vec2[off2+j*innerStride2], -1)
+				rhs := fn2(c, vec1[off1+j], vec2[off2+j*cols], -1)
 				for i := innerLength - 2; i >= 0; i-- {
-					Log.Printf(" rhs=%v [i=%d] vec1[%d] vec2[%d]", rhs, i, off1+i*innerStride1, off2+i*innerStride2)
-					lhs := fn2(c, vec1[off1+i*innerStride1], vec2[off2+i*innerStride2], -1)
+					lhs := fn2(c, vec1[off1+i], vec2[off2+i*cols], -1)
 					rhs = fn1(c, lhs, rhs, -1)
 				}
-				outVec[outOff] = rhs
-			} else if rank1 == 1 {
-				// Stop using shape1 and start using shape2 when rank1 == 1.
-				stride2, outStride := Product(shape2[1:]), Product(outShape[1:])
-				for i := 0; i < shape2[0]; i++ {
-					recurse(shape1, shape2[1:], off1, off2+i*stride2, outShape[1:], outOff+i*outStride)
-				}
-			} else {
-				stride1, outStride := Product(shape1[1:]), Product(outShape[1:])
-				for i := 0; i < shape1[0]; i++ {
-					recurse(shape1[1:], shape2, off1+i*stride1, off2, outShape[1:], outOff+i*outStride)
-				}
+				outVec[out] = rhs
 			}
 		}
-		recurse(shape1, shape2[1:], 0, 0, outShape, 0)
+		if parallel && allScalars(vec1) && allScalars(vec2) {
+			c.parallelFor(len(outVec), each)
+		} else {
+			each(0, len(outVec))
+		}
 		if len(outShape) == 0 {
 			return outVec[0] // Return scalar.
 		} else {
@@ -231,40 +237,27 @@ func MkInnerProduct(name string, fn1, fn2 DyadicFunc) DyadicFunc {
 func MkEachOpDyadic(name string, fn DyadicFunc) DyadicFunc {
 	return func(c *Context, a, b Val, axis int) Val {
 		if axis != -1 {
-			Log.Panicf("dyadic ~ op: cannot use axis: %d", axis)
-		}
-		amat, aok := a.(*Mat)
-		bmat, bok := b.(*Mat)
-		var vec []Val
-		switch {
-		case aok && bok:
-			if len(amat.S) != len(bmat.S) {
-				Log.Panicf("left and right matrix need same rank, but got shapes %v and %v", amat.S, bmat.S)
-			}
-			// TODO EQ
-			if Product(amat.S) != Product(bmat.S) {
-				Log.Panicf("left and right matrix need same shape, but got shapes %v and %v", amat.S, bmat.S)
-			}
-			for i, e := range amat.M {
-				x := fn(c, e, bmat.M[i], -1)
-				vec = append(vec, x)
-			}
-			return &Mat{vec, amat.S}
-		case aok:
-			for _, e := range amat.M {
-				x := fn(c, e, b, -1)
-				vec = append(vec, x)
-			}
-			return &Mat{vec, amat.S}
-		case bok:
-			for _, e := range bmat.M {
-				x := fn(c, a, e, -1)
-				vec = append(vec, x)
-			}
-			return &Mat{vec, bmat.S}
-		default:
-			return fn(c, a, b, -1)
+			Panicf(DomainError, "dyadic ~ op: cannot use axis: %d", axis)
 		}
+		cf := Conform(name, a, b)
+		n := cf.Len()
+		c.Alloc(n)
+		vec := make([]Val, n)
+		for i := 0; i < n; i++ {
+			x, y := cf.Pair(i)
+			vec[i] = fn(c, x, y, -1)
+		}
+		return cf.Result(vec)
+	}
+}
+
+// withPackedReduceOrScan adds a fast path for packed numeric arrays to a reduce or scan.
+func withPackedReduceOrScan(general MonadicFunc, kernel FuncCxCxCx, toScan bool) MonadicFunc {
+	return func(c *Context, b Val, axis int) Val {
+		if z := packedReduceOrScan(c, b, axis, kernel, toScan); z != nil {
+			return z
+		}
+		return general(c, b, axis)
 	}
 }
 
@@ -276,19 +269,19 @@ func MkReduceOrScanOp(name string, fn DyadicFunc, identity Val, toScan bool) Mon
 	return func(c *Context, a Val, axis int) Val {
 		mat, ok := a.(*Mat)
 		if !ok {
-			Log.Panicf("Cannot %s %s on non-matrix: %s", name, verb, a)
+			Panicf(DomainError, "Cannot %s %s on non-matrix: %s", name, verb, a)
 		}
 		oldRank := len(mat.S)
 		oldShape := mat.S
 		Log.Printf("oldShape %v oldRank %v for matrix %v", oldShape, oldRank, mat)
 		if oldRank == 0 {
-			Log.Panicf("Cannot %s %s on scalar: %s", name, verb, mat)
+			Panicf(RankError, "Cannot %s %s on scalar: %s", name, verb, mat)
 		}
 		if axis < 0 {
 			axis += oldRank
 		}
 		if axis < 0 || axis > oldRank-1 {
-			Log.Panicf("Reduce axis [%d] is bad for %s %s of rank %d", axis, name, verb, oldRank)
+			Panicf(IndexError, "Reduce axis [%d] is bad for %s %s of rank %d", axis, name, verb, oldRank)
 		}
 
 		var newShape []int
@@ -302,9 +295,9 @@ func MkReduceOrScanOp(name string, fn DyadicFunc, identity Val, toScan bool) Mon
 			}
 		}
 
-		newVecLen := Product(newShape)
+		newVecLen := c.AllocShape(newShape)
 		newVec := make([]Val, newVecLen)
-		oldVec := mat.M
+		oldVec := mat.Vals()
 
 		reduceStride, reduceLen := Product(oldShape[axis+1:]), oldShape[axis]
 		Log.Printf("Reduce Stride = %d", reduceStride)
@@ -318,6 +311,9 @@ func MkReduceOrScanOp(name string, fn DyadicFunc, identity Val, toScan bool) Mon
 				var reduction Val
 
 				if reduceLen == 0 {
+					if identity == nil && !toScan {
+						Panicf(DomainError, "Cannot %s reduce on an empty axis: no identity is known", name)
+					}
 					reduction = identity
 				} else {
 					// j is 0:
@@ -366,7 +362,7 @@ func MkReduceOrScanOp(name string, fn DyadicFunc, identity Val, toScan bool) Mon
 		if len(newShape) == 0 {
 			return newVec[0]
 		} else {
-			return &Mat{newVec, newShape}
+			return &Mat{M: newVec, S: newShape}
 		}
 	}
 }
@@ -393,38 +389,31 @@ func cxcxRect(ca, cb complex128) complex128 {
 }
 
 func dyadicRho(c *Context, a Val, b Val, axis int) Val {
-	spec := GetVectorOfScalarInts(a)
-	outSize := Product(spec)
+	spec := c.ScalarInts(a)
+	outSize := c.AllocShape(spec)
 	bm := asMat(b)
 
 	if outSize > 0 && bm == nil {
-		Log.Panicf("Cannot resize empty matrix to shape %v", spec)
+		Panicf(LengthError, "Cannot resize empty matrix to shape %v", spec)
 	}
 
-	if len(spec) == 0 {
-		return bm.M[0]
-	}
-
-	var vec []Val
-	vec, _ = recursiveFill(spec, bm.M, vec, 0)
-	return &Mat{M: vec, S: spec}
-}
-
-func recursiveFill(shape []int, source []Val, vec []Val, i int) ([]Val, int) {
-	modulus := len(source)
-	head := shape[0]
-	tail := shape[1:]
-	if len(tail) > 0 {
-		for j := 0; j < head; j++ {
-			vec, i = recursiveFill(tail, source, vec, i)
+	vec := make([]Val, outSize)
+	if outSize > 0 {
+		source := bm.Vals()
+		if len(source) == 0 {
+			// Like take, reshaping an empty matrix fills with zeros.
+			source = []Val{FillVal(bm)}
 		}
-	} else {
-		for j := 0; j < head; j++ {
-			vec = append(vec, source[i])
-			i = (i + 1) % modulus
+		if len(spec) == 0 {
+			return source[0]
 		}
+		c.parallelFor(outSize, func(lo, hi int) {
+			for i := lo; i < hi; i++ {
+				vec[i] = source[i%len(source)]
+			}
+		})
 	}
-	return vec, i
+	return &Mat{M: vec, S: spec}
 }
 
 type FuncFloatFloatBool func(float64, float64) bool
@@ -471,93 +460,210 @@ func WrapCxBoolDyadic(fn FuncCxCxBool) DyadicFunc {
 	}
 }
 
-func SameRank(a, b *Mat) bool {
-	return len(a.S) == len(b.S)
+func cxAdd(a, b complex128) complex128 { return a + b }
+func cxSub(a, b complex128) complex128 { return a - b }
+func cxMul(a, b complex128) complex128 { return a * b }
+func cxDiv(a, b complex128) complex128 { return a / b }
+
+// cxKernels are the standard scalar functions that reduce, scan,
+// and inner product can apply directly to packed arrays.
+var cxKernels = map[string]FuncCxCxCx{
+	"+":   cxAdd,
+	"-":   cxSub,
+	"*":   cxMul,
+	"/":   cxDiv,
+	"div": cxDiv,
 }
-func SameShape(a, b *Mat) bool {
-	if !SameRank(a, b) {
-		return false
+
+// CxKernel finds the kernel of a standard scalar function for packed arrays,
+// unless the name has been redefined.
+func (c *Context) CxKernel(name string) (FuncCxCxCx, bool) {
+	if !c.isStandardDyadic(name) {
+		return nil, false
 	}
-	for i := 0; i < len(a.S); i++ {
-		if a.S[i] != b.S[i] {
-			return false
+	fn, ok := cxKernels[name]
+	return fn, ok
+}
+
+// WrapMatCxDyadic is WrapMatMatDyadicParallel(WrapCxDyadic(fn)),
+// with a fast path for packed numeric arrays.
+func WrapMatCxDyadic(fn FuncCxCxCx) DyadicFunc {
+	return withPackedCxDyadic(WrapMatMatDyadicParallel(WrapCxDyadic(fn)), fn)
+}
+
+// WrapMatExactCxDyadic is like WrapMatCxDyadic, but uses exact for Exact numbers.
+func WrapMatExactCxDyadic(exact FuncExactExactVal, fn FuncCxCxCx) DyadicFunc {
+	return withPackedCxDyadic(WrapMatMatDyadicParallel(WrapExactDyadic(exact, WrapCxDyadic(fn))), fn)
+}
+
+func withPackedCxDyadic(general DyadicFunc, fn FuncCxCxCx) DyadicFunc {
+	kernel := func(x, y complex128) (complex128, bool) {
+		return fn(x, y), true
+	}
+	return func(c *Context, a, b Val, axis int) Val {
+		if z := packedDyadic(c, a, b, kernel); z != nil {
+			return z
 		}
+		return general(c, a, b, axis)
 	}
-	return true
 }
 
-func WrapMatMatDyadic(fn DyadicFunc) DyadicFunc {
+// WrapMatFloatDyadic is WrapMatMatDyadicParallel(WrapFloatDyadic(fn)),
+// with a fast path for packed real arrays.
+func WrapMatFloatDyadic(fn FuncFloatFloatFloat) DyadicFunc {
+	return withPackedFloatDyadic(WrapMatMatDyadicParallel(WrapFloatDyadic(fn)), fn)
+}
+
+// WrapMatExactFloatDyadic is like WrapMatFloatDyadic, but uses exact for Exact numbers.
+func WrapMatExactFloatDyadic(exact FuncExactExactVal, fn FuncFloatFloatFloat) DyadicFunc {
+	return withPackedFloatDyadic(WrapMatMatDyadicParallel(WrapExactDyadic(exact, WrapFloatDyadic(fn))), fn)
+}
+
+func withPackedFloatDyadic(general DyadicFunc, fn FuncFloatFloatFloat) DyadicFunc {
+	kernel := func(x, y complex128) (complex128, bool) {
+		if imag(x) != 0 || imag(y) != 0 {
+			return 0, false
+		}
+		return complex(fn(real(x), real(y)), 0), true
+	}
 	return func(c *Context, a, b Val, axis int) Val {
-		switch x := a.(type) {
-		case *Mat:
-			switch y := b.(type) {
-			case *Mat:
-				// same shape or wrong.
-				if SameShape(x, y) {
-
-					n := len(x.M)
-					vec := make([]Val, n)
-
-					for i := 0; i < n; i++ {
-						x1 := x.M[i].GetScalarOrNil()
-						if x1 == nil {
-							Log.Panicf("LHS not a scalar at matrix offset %d: %s", i, x1)
-						}
-						y1 := y.M[i].GetScalarOrNil()
-						if y1 == nil {
-							Log.Panicf("RHS not a scalar at matrix offset %d: %s", i, y1)
-						}
-						vec[i] = fn(c, x1, y1, axis)
-					}
+		if z := packedDyadic(c, a, b, kernel); z != nil {
+			return z
+		}
+		return general(c, a, b, axis)
+	}
+}
 
-					return &Mat{M: vec, S: x.S}
-				}
-			}
-			ys := b.GetScalarOrNil()
-			if ys != nil {
-
-				n := len(x.M)
-				vec := make([]Val, n)
-				for i := 0; i < n; i++ {
-					x1 := x.M[i].GetScalarOrNil()
-					if x1 == nil {
-						Log.Panicf("LHS not a scalar at matrix offset %d: %s", i, x1)
-					}
-					vec[i] = fn(c, x1, ys, axis)
-				}
+// WrapMatFloatBoolDyadic is WrapMatMatDyadicParallel(WrapFloatBoolDyadic(fn)),
+// with a fast path for packed real arrays.
+func WrapMatFloatBoolDyadic(fn FuncFloatFloatBool) DyadicFunc {
+	return WrapMatFloatDyadic(func(a, b float64) float64 {
+		return real(Bool2Cx(fn(a, b)))
+	})
+}
 
-				return &Mat{M: vec, S: x.S}
+// WrapMatCompareDyadic is WrapMatMatDyadicParallel(WrapCompareDyadic(fn)),
+// with a fast path for packed real arrays.
+func WrapMatCompareDyadic(fn func(cmp int) bool) DyadicFunc {
+	general := WrapMatMatDyadicParallel(WrapCompareDyadic(fn))
+	return func(c *Context, a, b Val, axis int) Val {
+		z := packedDyadic(c, a, b, func(x, y complex128) (complex128, bool) {
+			if imag(x) != 0 || imag(y) != 0 {
+				return 0, false
 			}
+			return Bool2Cx(fn(c.CompareFloat(real(x), real(y)))), true
+		})
+		if z != nil {
+			return z
 		}
+		return general(c, a, b, axis)
+	}
+}
 
-		xs := a.GetScalarOrNil()
-		if xs == nil {
-			Log.Panicf("LHS neither matching matrix nor scalar: %s", a)
+// WrapMatEqualDyadic is WrapMatMatDyadicParallel(WrapEqualDyadic(negate)),
+// with a fast path for packed numeric arrays.
+func WrapMatEqualDyadic(negate bool) DyadicFunc {
+	general := WrapMatMatDyadicParallel(WrapEqualDyadic(negate))
+	return func(c *Context, a, b Val, axis int) Val {
+		z := packedDyadic(c, a, b, func(x, y complex128) (complex128, bool) {
+			return Bool2Cx(negate != c.EqualCx(x, y)), true
+		})
+		if z != nil {
+			return z
 		}
+		return general(c, a, b, axis)
+	}
+}
 
-		switch y := b.(type) {
-		case *Mat:
-			if xs != nil {
+// WrapCompareDyadic compares real numbers within the comparison tolerance,
+// or exactly if they are Exact, and fn tells if the result of CompareFloat means true.
+func WrapCompareDyadic(fn func(cmp int) bool) DyadicFunc {
+	return func(c *Context, a, b Val, axis int) Val {
+		if x, y, ok := exactPair(a, b); ok {
+			return &Num{Bool2Cx(fn(x.Cmp(y)))}
+		}
+		cmp := c.CompareFloat(a.GetScalarFloat(), b.GetScalarFloat())
+		return &Num{Bool2Cx(fn(cmp))}
+	}
+}
 
-				n := len(y.M)
-				vec := make([]Val, n)
-				for i := 0; i < n; i++ {
-					y1 := y.M[i].GetScalarOrNil()
-					if y1 == nil {
-						Log.Panicf("RHS not a scalar at matrix offset %d: %s", i, y1)
-					}
-					vec[i] = fn(c, xs, y1, axis)
-				}
+// WrapEqualDyadic compares numbers by value within the comparison tolerance,
+// and other scalars (such as chars) by Compare.  Scalars of different kinds are never equal.
+func WrapEqualDyadic(negate bool) DyadicFunc {
+	return func(c *Context, a, b Val, axis int) Val {
+		return &Num{Bool2Cx(negate != c.Equal(a, b))}
+	}
+}
+
+// ScalarEqual is like Context.Equal, but exact.
+func ScalarEqual(a, b Val) bool {
+	if a.ValEnum() != b.ValEnum() {
+		return false
+	}
+	if x, y, ok := exactPair(a, b); ok {
+		return x.Cmp(y) == 0
+	}
+	if a.ValEnum() == NumVal {
+		return a.GetScalarCx() == b.GetScalarCx()
+	}
+	return a.Compare(b) == 0
+}
+
+// FillVal is the value used to pad a matrix by take or expand:
+// a space for char matrices, otherwise zero.
+func FillVal(mat *Mat) Val {
+	if mat.Len() > 0 && mat.Vals()[0].ValEnum() == CharVal {
+		return &Char{' '}
+	}
+	return Zero
+}
+
+func SameRank(a, b *Mat) bool {
+	return len(a.S) == len(b.S)
+}
+func SameShape(a, b *Mat) bool {
+	return sameInts(a.S, b.S)
+}
+
+// WrapMatMatDyadic makes a scalar function work on conforming arrays, element by element,
+// calling fn on the elements in order, in one goroutine.
+func WrapMatMatDyadic(fn DyadicFunc) DyadicFunc {
+	return wrapMatMatDyadic(fn, false)
+}
 
-				return &Mat{M: vec, S: y.S}
+// WrapMatMatDyadicParallel is like WrapMatMatDyadic, but for large arrays,
+// calls fn from several goroutines at once, so fn must be safe for that.
+// The standard scalar functions use it.
+func WrapMatMatDyadicParallel(fn DyadicFunc) DyadicFunc {
+	return wrapMatMatDyadic(fn, true)
+}
+
+func wrapMatMatDyadic(fn DyadicFunc, parallel bool) DyadicFunc {
+	return func(c *Context, a, b Val, axis int) Val {
+		cf := Conform("", a, b)
+		n := cf.Len()
+		c.Alloc(n)
+		vec := make([]Val, n)
+		each := func(lo, hi int) {
+			for i := lo; i < hi; i++ {
+				x, y := cf.Pair(i)
+				x1 := x.GetScalarOrNil()
+				if x1 == nil {
+					Panicf(DomainError, "LHS not a scalar at matrix offset %d: %s", i, x)
+				}
+				y1 := y.GetScalarOrNil()
+				if y1 == nil {
+					Panicf(DomainError, "RHS not a scalar at matrix offset %d: %s", i, y)
+				}
+				vec[i] = fn(c, x1, y1, axis)
 			}
 		}
-
-		ys := b.GetScalarOrNil()
-		if ys == nil {
-			Log.Panicf("RHS neither matrix nor scalar: %s", b)
+		if parallel {
+			c.parallelFor(n, each)
+		} else {
+			each(0, n)
 		}
-		return fn(c, xs, ys, axis)
+		return cf.Result(vec)
 	}
 }
 
@@ -569,14 +675,14 @@ func GetVectorOfScalarVals(a Val) []Val {
 		// degenerate vector from scalar.
 		y := a.GetScalarOrNil()
 		if y == nil {
-			Log.Panicf("GetVectorOfScalarVals: neither vector nor scalar: %v", a)
+			Panicf(DomainError, "GetVectorOfScalarVals: neither vector nor scalar: %v", a)
 		}
 		z = append(z, y)
 	} else {
-		for _, x := range mat.M {
+		for _, x := range mat.Vals() {
 			y := x.GetScalarOrNil()
 			if y == nil {
-				Log.Panicf("GetVectorOfScalarVals: item not scalar")
+				Panicf(DomainError, "GetVectorOfScalarVals: item not scalar")
 			}
 			z = append(z, y)
 		}
@@ -593,7 +699,7 @@ func GetVectorOfScalarFloats(a Val) []float64 {
 		z = append(z, a.GetScalarFloat())
 	} else {
 		// convert vector to float64s.
-		for _, x := range mat.M {
+		for _, x := range mat.Vals() {
 			z = append(z, x.GetScalarFloat())
 		}
 	}
@@ -609,7 +715,7 @@ func GetVectorOfScalarInts(a Val) []int {
 		z = append(z, a.GetScalarInt())
 	} else {
 		// convert vector to ints.
-		for _, x := range mat.M {
+		for _, x := range mat.Vals() {
 			z = append(z, x.GetScalarInt())
 		}
 	}
@@ -619,7 +725,7 @@ func GetVectorOfScalarInts(a Val) []int {
 func dyadicRot(c *Context, a Val, b Val, axis int) Val {
 	mat, bok := b.(*Mat)
 	if !bok {
-		Log.Panicf("Cannot rotate a non-matrix")
+		Panicf(DomainError, "Cannot rotate a non-matrix")
 	}
 	shape := mat.S     // in & out shape.
 	rank := len(shape) // in & out rank.
@@ -635,23 +741,23 @@ func dyadicRot(c *Context, a Val, b Val, axis int) Val {
 	amat, aok := a.(*Mat)
 	if aok {
 		if len(amat.S)+1 != len(mat.S) {
-			Log.Panicf("rotate: LHS has shape %v; RHS has shape %v; axis is %d; shape of LHS should be 1 shorter than shape of RHS", amat.S, mat.S, axis)
+			Panicf(RankError, "rotate: LHS has shape %v; RHS has shape %v; axis is %d; shape of LHS should be 1 shorter than shape of RHS", amat.S, mat.S, axis)
 		}
-		spec = GetVectorOfScalarInts(a)
+		spec = c.ScalarInts(a)
 		j := 0
 		for i, e := range mat.S {
 			if i == axis {
 				// skip chosen axis.
 			} else {
 				if amat.S[j] != e {
-					Log.Panicf("rotate: LHS has shape %v; RHS has shape %v; axis is %d; dim %d of LHS should match dim %d of RHS", amat.S, mat.S, axis, j, i)
+					Panicf(LengthError, "rotate: LHS has shape %v; RHS has shape %v; axis is %d; dim %d of LHS should match dim %d of RHS", amat.S, mat.S, axis, j, i)
 				}
 				specShape = append(specShape, e)
 				j++
 			}
 		}
 	} else {
-		r := a.GetScalarInt()
+		r := c.ScalarInt(a)
 		for i, e := range mat.S {
 			if i != axis {
 				specShape = append(specShape, e)
@@ -663,7 +769,7 @@ func dyadicRot(c *Context, a Val, b Val, axis int) Val {
 		}
 	}
 
-	inVec := mat.M
+	inVec := mat.Vals()
 	var outVec []Val
 
 	var recurse func(shape, specShape []int, inOff int, spec []int, deferStart, deferStride, deferLen int)
@@ -716,17 +822,17 @@ func dyadicDrop(c *Context, a Val, b Val, axis int) Val {
 }
 func dyadicTakeOrDrop(c *Context, a Val, b Val, axis int, dropping bool) Val {
 	if axis != -1 {
-		Log.Panicf("Cannot specify axis for take or drop: %d", axis)
+		Panicf(DomainError, "Cannot specify axis for take or drop: %d", axis)
 	}
-	spec := GetVectorOfScalarInts(a)
+	spec := c.ScalarInts(a)
 	mat, ok := b.(*Mat)
 	if !ok {
-		Log.Panicf("Dyadic Take wants matrix on right, but got %#v", b)
+		Panicf(DomainError, "Dyadic Take wants matrix on right, but got %#v", b)
 	}
-	inVec := mat.M
+	inVec := mat.Vals()
 	inShape := mat.S
 	if len(spec) != len(inShape) {
-		Log.Panicf("Dyadic Take wants them to be the same, but len(LHS) == %d and len(shape(RHS)) == %d", len(spec), len(inShape))
+		Panicf(RankError, "Dyadic Take wants them to be the same, but len(LHS) == %d and len(shape(RHS)) == %d", len(spec), len(inShape))
 	}
 
 	// Figure out the outShape (how many to copy) and the inStart (where to start copying from).
@@ -740,7 +846,7 @@ func dyadicTakeOrDrop(c *Context, a Val, b Val, axis int, dropping bool) Val {
 		if k > sz {
 			if dropping {
 				// TODO
-				Log.Panicf("Dyadic Drop LHS[%d] abs too big, is %d; RHS shape is %v", i, spec[i], inShape)
+				Panicf(LengthError, "Dyadic Drop LHS[%d] abs too big, is %d; RHS shape is %v", i, spec[i], inShape)
 			} else {
 				if spec[i] > 0 {
 					post = k - sz
@@ -762,7 +868,8 @@ func dyadicTakeOrDrop(c *Context, a Val, b Val, axis int, dropping bool) Val {
 		prePad = append(prePad, pre)
 		postPad = append(postPad, post)
 	}
-	outVec := make([]Val, Product(outShape))
+	outVec := make([]Val, c.AllocShape(outShape))
+	fill := FillVal(mat)
 
 	Log.Printf("inStart %v", inStart)
 	Log.Printf("inShape %v", inShape)
@@ -773,7 +880,7 @@ func dyadicTakeOrDrop(c *Context, a Val, b Val, axis int, dropping bool) Val {
 		if len(inStart) == 0 {
 			Log.Printf("CP %d <= %d", outOff, inOff)
 			if zeroing {
-				outVec[outOff] = Zero
+				outVec[outOff] = fill
 			} else {
 				outVec[outOff] = inVec[inOff]
 			}
@@ -807,15 +914,15 @@ func dyadicCompress(c *Context, a Val, b Val, axis int) Val {
 func dyadicExpandOrCompress(c *Context, a Val, b Val, axis int, compressing bool, name string) Val {
 	mat, ok := b.(*Mat)
 	if !ok {
-		Log.Panicf("dyadic %s wants matrix on right, but got %#v", name, b)
+		Panicf(DomainError, "dyadic %s wants matrix on right, but got %#v", name, b)
 	}
-	inVec := mat.M
+	inVec := mat.Vals()
 	inShape := mat.S
 	origInRank := len(inShape)
 	axis = Mod(axis, origInRank)
 	srcAxisShape := inShape[axis]
 
-	spec := GetVectorOfScalarInts(a)
+	spec := c.ScalarInts(a)
 	// In the plan, 0 upwards mean copy over that source position.
 	// So these special negative numbers can mean drop (for compress) & insert (for expand).
 	const kDrop = -1
@@ -835,13 +942,13 @@ func dyadicExpandOrCompress(c *Context, a Val, b Val, axis int, compressing bool
 			}
 		case 1:
 			if srcPos == srcAxisShape {
-				Log.Panicf("Dyadic %s axis is not wide enough: got LHS == %v; RHS shape is %v", name, spec, inShape)
+				Panicf(LengthError, "Dyadic %s axis is not wide enough: got LHS == %v; RHS shape is %v", name, spec, inShape)
 			}
 			plan = append(plan, srcPos)
 			srcPos++
 			destLen++
 		default:
-			Log.Panicf("dyadic %s has non-boolean element on LHS: %v", name, spec)
+			Panicf(DomainError, "dyadic %s has non-boolean element on LHS: %v", name, spec)
 		}
 	}
 
@@ -853,14 +960,15 @@ func dyadicExpandOrCompress(c *Context, a Val, b Val, axis int, compressing bool
 			outShape = append(outShape, a)
 		}
 	}
-	outVec := make([]Val, Product(outShape))
+	outVec := make([]Val, c.AllocShape(outShape))
+	fill := FillVal(mat)
 
 	var recurse func(inShape []int, inOff int, outShape []int, outOff int)
 	recurse = func(inShape []int, inOff int, outShape []int, outOff int) {
 		if len(outShape) == 0 {
 			if inOff == -1 {
-				Log.Printf("ZERO %d", outOff)
-				outVec[outOff] = &Num{0.0}
+				Log.Printf("FILL %d", outOff)
+				outVec[outOff] = fill
 			} else {
 				Log.Printf("CP %d <= %d", outOff, inOff)
 				outVec[outOff] = inVec[inOff]
@@ -900,27 +1008,27 @@ func dyadicExpandOrCompress(c *Context, a Val, b Val, axis int, compressing bool
 func dyadicLaminate(c *Context, a Val, b Val, axis int) Val {
 	ma, ok := a.(*Mat)
 	if !ok {
-		Log.Panicf("Dyadic `laminate` wants matrix on left, but got %#v", a)
+		Panicf(DomainError, "Dyadic `laminate` wants matrix on left, but got %#v", a)
 	}
 
 	mb, ok := b.(*Mat)
 	if !ok {
-		Log.Panicf("Dyadic `laminate` wants matrix on right, but got %#v", b)
+		Panicf(DomainError, "Dyadic `laminate` wants matrix on right, but got %#v", b)
 	}
 
-	aVec := ma.M
+	aVec := ma.Vals()
 	aShape := ma.S
 	aRank := len(aShape)
-	bVec := mb.M
+	bVec := mb.Vals()
 	bShape := mb.S
 	bRank := len(bShape)
 
 	if aRank != bRank {
-		Log.Panicf("Dyadic `,` wants same shape, but left shape is %v and right shape is %v", aShape, bShape)
+		Panicf(LengthError, "Dyadic `,` wants same shape, but left shape is %v and right shape is %v", aShape, bShape)
 	}
 	for i := 0; i < aRank; i++ {
 		if aShape[i] != bShape[i] {
-			Log.Panicf("Dyadic `,` wants same shape, but left shape is %v and right shape is %v", aShape, bShape)
+			Panicf(LengthError, "Dyadic `,` wants same shape, but left shape is %v and right shape is %v", aShape, bShape)
 		}
 	}
 	// axis is the newly created dimension, from 0 to aRank+1 (incl).
@@ -938,7 +1046,7 @@ func dyadicLaminate(c *Context, a Val, b Val, axis int) Val {
 	}
 	Log.Printf("Laminate: lhs %v rhs %v out %v", aShape, bShape, outShape)
 
-	outVec := make([]Val, Product(outShape))
+	outVec := make([]Val, c.AllocShape(outShape))
 	newDimStride := Product(aShape[axis:])
 
 	var recurse func(inShape []int, inOff int, outShape []int, outOff int)
@@ -970,7 +1078,7 @@ func dyadicCatenate(c *Context, a Val, b Val, axis int) Val {
 	mb, bok := b.(*Mat)
 	if !aok && !bok {
 		// Concat two scalars into a pair.
-		return &Mat{[]Val{a, b}, []int{2}}
+		return &Mat{M: []Val{a, b}, S: []int{2}}
 	}
 
 	if !aok {
@@ -979,7 +1087,7 @@ func dyadicCatenate(c *Context, a Val, b Val, axis int) Val {
 		copy(newShape, mb.S)
 		axis = Mod(axis, n)
 		newShape[axis] = 1
-		ma = &Mat{RepeatVal(a, Product(newShape)), newShape}
+		ma = &Mat{M: RepeatVal(a, Product(newShape)), S: newShape}
 	}
 
 	if !bok {
@@ -988,18 +1096,18 @@ func dyadicCatenate(c *Context, a Val, b Val, axis int) Val {
 		copy(newShape, ma.S)
 		axis = Mod(axis, n)
 		newShape[axis] = 1
-		mb = &Mat{RepeatVal(b, Product(newShape)), newShape}
+		mb = &Mat{M: RepeatVal(b, Product(newShape)), S: newShape}
 	}
 
-	aVec := ma.M
+	aVec := ma.Vals()
 	aShape := ma.S
 	aRank := len(aShape)
-	bVec := mb.M
+	bVec := mb.Vals()
 	bShape := mb.S
 	bRank := len(bShape)
 
 	if aRank != bRank {
-		Log.Panicf("Dyadic `,` wants same rank, but left shape is %v and right shape is %v", aShape, bShape)
+		Panicf(RankError, "Dyadic `,` wants same rank, but left shape is %v and right shape is %v", aShape, bShape)
 	}
 	axis = Mod(axis, aRank)
 
@@ -1009,14 +1117,14 @@ func dyadicCatenate(c *Context, a Val, b Val, axis int) Val {
 			outShape = append(outShape, aShape[i]+bShape[i])
 		} else {
 			if aShape[i] != bShape[i] {
-				Log.Panicf("Dyadic `,` wants same shape except for axis dimension %d, but left shape is %v and right shape is %v", axis, aShape, bShape)
+				Panicf(LengthError, "Dyadic `,` wants same shape except for axis dimension %d, but left shape is %v and right shape is %v", axis, aShape, bShape)
 			}
 			outShape = append(outShape, aShape[i])
 		}
 	}
 	Log.Printf("Concatenate: lhs %v rhs %v out %v", aShape, bShape, outShape)
 
-	outVec := make([]Val, Product(outShape))
+	outVec := make([]Val, c.AllocShape(outShape))
 	var inVec []Val
 
 	var recurse func(inShape []int, inOff int, outShape []int, outOff int)
@@ -1045,16 +1153,16 @@ func dyadicCatenate(c *Context, a Val, b Val, axis int) Val {
 func dyadicTranspose(c *Context, a Val, b Val, axis int) Val {
 	mat, ok := b.(*Mat)
 	if !ok {
-		Log.Panicf("Dyadic `transpose` wants matrix on right, but got %#v", b)
+		Panicf(DomainError, "Dyadic `transpose` wants matrix on right, but got %#v", b)
 	}
 
-	inVec := mat.M
+	inVec := mat.Vals()
 	inShape := mat.S
 	inRank := len(inShape)
 
-	spec := GetVectorOfScalarInts(a)
+	spec := c.ScalarInts(a)
 	if len(spec) != inRank {
-		Log.Panicf("Dyadic `transpose` wants length of lhs %d to match rank of rhs %d", len(spec), inRank)
+		Panicf(RankError, "Dyadic `transpose` wants length of lhs %d to match rank of rhs %d", len(spec), inRank)
 	}
 	for i := range spec {
 		spec[i] = Mod(spec[i], inRank)
@@ -1063,7 +1171,7 @@ func dyadicTranspose(c *Context, a Val, b Val, axis int) Val {
 	outRank := 0
 	for _, e := range spec {
 		if e < 0 || e >= len(spec) {
-			Log.Panicf("Dyadic `transpose` finds %d on lhs, not a valid dimensio in lhs %v", e, spec)
+			Panicf(IndexError, "Dyadic `transpose` finds %d on lhs, not a valid dimensio in lhs %v", e, spec)
 		}
 		if e+1 > outRank {
 			outRank = e + 1
@@ -1109,12 +1217,12 @@ func (p ValSlice) Len() int {
 func (p ValSlice) Less(i, j int) bool {
 	na, ok := a.(*Num)
 	if !ok {
-		Log.Panicf("Expected a number, but got %#v", a)
+		Panicf(DomainError, "Expected a number, but got %#v", a)
 	}
 
 	nb, ok := b.(*Num)
 	if !ok {
-		Log.Panicf("Expected a number, but got %#v", b)
+		Panicf(DomainError, "Expected a number, but got %#v", b)
 	}
 
 	return na.F < nb.F
@@ -1125,47 +1233,211 @@ func (p ValSlice) Swap(i, j int) {
 }
 */
 
-func dyadicMember(c *Context, a Val, b Val, axis int) Val {
-	// TODO: allow scalar, particularly on LHS.
-	// TODO: value.go should compare any Val, not just *Num.
-	mata, ok := a.(*Mat)
-	if !ok {
-		Log.Panicf("Dyadic `member` wants matrix on left, but got %#v", a)
+// hashIndexMin is the size of array worth hashing, for iota and member.
+const hashIndexMin = 32
+
+// valIndex finds the first index of a value in a list of values, using Context.Equal.
+// Small lists are searched linearly.  Large lists of numbers and chars are hashed,
+// and other large lists are searched in a stably sorted copy.
+// With a comparison tolerance, numbers are hashed only if they are all near integers
+// smaller than hashBound, and hash hits are checked with Context.Equal.
+type valIndex struct {
+	c      *Context
+	vals   []Val
+	hash   map[interface{}]int
+	sorted []int
+}
+
+// hashKey is a map key for a number or char, or ok==false for other values.
+// The key types differ, so a number never matches a char.
+// With a comparison tolerance, a number is keyed by its nearest integer,
+// and ok is false if it is not near one, or not smaller than hashBound.
+func (o *valIndex) hashKey(v Val) (key interface{}, ok bool) {
+	switch t := v.(type) {
+	case *Num:
+		return o.numKey(t.F)
+	case Num:
+		return o.numKey(t.F)
+	case *Char:
+		return t.R, true
+	case Char:
+		return t.R, true
+	case *Exact:
+		return o.exactKey(*t)
+	case Exact:
+		return o.exactKey(t)
 	}
+	return nil, false
+}
 
-	matb, ok := b.(*Mat)
-	if !ok {
-		Log.Panicf("Dyadic `member` wants matrix on right, but got %#v", b)
+// exactKey keys an Exact number like the Num it equals, so ok is false if there is none.
+func (o *valIndex) exactKey(x Exact) (key interface{}, ok bool) {
+	if f, exact := x.Float64(); exact {
+		return o.numKey(complex(f, 0))
 	}
+	return nil, false
+}
 
-	aVec := mata.M
-	aShape := mata.S
-	outShape := aShape
-	outVec := make([]Val, len(aVec))
+// hashBound is the magnitude below which tolerantly equal numbers near integers
+// are near the same integers, so they can be keyed by them.
+// Integers differing by 1 are tolerantly equal at magnitude 1/CompareTolerance,
+// and numbers within the tolerance of them may be equal at a third of that.
+func (o *valIndex) hashBound() float64 {
+	return 0.25 / o.c.CompareTolerance
+}
 
-	bVec := matb.M
-	floats := make(sort.Float64Slice, len(bVec))
-	for i, e := range bVec {
-		num, ok := e.(*Num)
+func (o *valIndex) numKey(x complex128) (key interface{}, ok bool) {
+	if o.c.CompareTolerance == 0 {
+		return x, true
+	}
+	if cmplx.Abs(x) >= o.hashBound() {
+		return nil, false
+	}
+	re, ok1 := o.c.nearInt(real(x))
+	im, ok2 := o.c.nearInt(imag(x))
+	return complex(re, im), ok1 && ok2
+}
+
+// mayEqualHashed tells if a number without a hash key could be tolerantly equal
+// to a hashed number, that is, to a number near an integer and smaller than hashBound.
+func (o *valIndex) mayEqualHashed(x complex128) bool {
+	t := o.c.CompareTolerance
+	a := cmplx.Abs(x)
+	if t == 0 || t > 0.1 {
+		return true
+	}
+	if a >= 2*o.hashBound() {
+		return false
+	}
+	// Being equal to a number near an integer, x must be within 3t|x| of that integer.
+	nearest := complex(math.Round(real(x)), math.Round(imag(x)))
+	return cmplx.Abs(x-nearest) <= 3*t*math.Max(a, 1)
+}
+
+func newValIndex(c *Context, vals []Val) *valIndex {
+	z := &valIndex{c: c, vals: vals}
+	if len(vals) < hashIndexMin {
+		return z
+	}
+
+	hash := make(map[interface{}]int)
+	for i := len(vals) - 1; i >= 0; i-- {
+		key, ok := z.hashKey(vals[i])
 		if !ok {
-			Log.Panicf("Dyadic `member` RHS element @%d not a number: %v", i, e)
+			hash = nil
+			break
+		}
+		hash[key] = i // Lowest index wins.
+	}
+	if hash != nil {
+		z.hash = hash
+		return z
+	}
+	if c.CompareTolerance != 0 {
+		for _, v := range vals {
+			if v.ValEnum() == NumVal {
+				return z // Sorting cannot find tolerantly equal numbers.
+			}
 		}
-		floats[i] = num.GetScalarFloat()
 	}
-	floats.Sort()
 
-	for i, e := range aVec {
-		num, ok := e.(*Num)
-		if !ok {
-			Log.Panicf("Dyadic `member` RHS element @%d not a number: %v", i, e)
+	z.sorted = make([]int, len(vals))
+	for i := range z.sorted {
+		z.sorted[i] = i
+	}
+	sort.SliceStable(z.sorted, func(i, j int) bool {
+		return Compare(vals[z.sorted[i]], vals[z.sorted[j]]) < 0
+	})
+	return z
+}
+
+// Find returns the first index of a value equal to x, or -1.
+func (o *valIndex) Find(x Val) int {
+	switch {
+	case o.hash != nil:
+		if key, ok := o.hashKey(x); ok {
+			i, ok := o.hash[key]
+			if !ok {
+				return -1
+			}
+			if o.c.CompareTolerance == 0 || o.c.Equal(o.vals[i], x) {
+				return i
+			}
+			// Numbers near the same integer need not be tolerantly equal,
+			// so a later one with the key may still be; search them all.
+		} else {
+			switch t := x.(type) {
+			case *Num:
+				if !o.mayEqualHashed(t.F) {
+					return -1
+				}
+			case Num:
+				if !o.mayEqualHashed(t.F) {
+					return -1
+				}
+			case *Exact, Exact:
+				// Rare enough to search them all.
+			default:
+				return -1
+			}
+		}
+	case o.sorted != nil:
+		n := len(o.sorted)
+		j := sort.Search(n, func(j int) bool { return Compare(o.vals[o.sorted[j]], x) >= 0 })
+		if j < n && Compare(o.vals[o.sorted[j]], x) == 0 {
+			return o.sorted[j]
+		}
+		return -1
+	}
+	for i, v := range o.vals {
+		if o.c.Equal(v, x) {
+			return i
 		}
-		j := sort.SearchFloat64s(floats, num.GetScalarFloat())
+	}
+	return -1
+}
+
+// shapeAndVals returns the shape and elements of a value; a scalar has an empty shape.
+func shapeAndVals(v Val) ([]int, []Val) {
+	if mat, ok := v.(*Mat); ok {
+		return mat.S, mat.Vals()
+	}
+	return nil, []Val{v}
+}
 
-		found := (j < len(floats) && floats[j] == num.GetScalarFloat())
+// dyadicMember tells for each item of the LHS whether it is anywhere in the RHS.
+func dyadicMember(c *Context, a Val, b Val, axis int) Val {
+	aShape, aVec := shapeAndVals(a)
+	_, bVec := shapeAndVals(b)
+
+	index := newValIndex(c, bVec)
+	outVec := make([]Val, len(aVec))
+	for i, e := range aVec {
+		found := index.Find(e) >= 0
 		outVec[i] = &Num{complex(boolf(found), 0)}
 	}
+	return matOrScalar(aShape, outVec)
+}
+
+// dyadicIota gives for each item of the RHS the first index where it occurs in the LHS vector,
+// or the length of the LHS if it does not occur.
+func dyadicIota(c *Context, a Val, b Val, axis int) Val {
+	aShape, aVec := shapeAndVals(a)
+	if len(aShape) > 1 {
+		Panicf(RankError, "Dyadic `iota` wants vector on left, but got shape %v", aShape)
+	}
+	bShape, bVec := shapeAndVals(b)
 
-	return &Mat{outVec, outShape}
+	index := newValIndex(c, aVec)
+	outVec := make([]Val, len(bVec))
+	for i, e := range bVec {
+		j := index.Find(e)
+		if j < 0 {
+			j = len(aVec)
+		}
+		outVec[i] = IntNum(j)
+	}
+	return matOrScalar(bShape, outVec)
 }
 
 func RepeatVal(a Val, n int) []Val {
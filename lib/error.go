@@ -0,0 +1,192 @@
+package livy
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ErrorKind classifies a LivyError, in the tradition of APL error reports.
+type ErrorKind string
+
+const (
+	UnknownError ErrorKind = ""
+	DomainError  ErrorKind = "DOMAIN" // An argument has the wrong type or value.
+	RankError    ErrorKind = "RANK"   // An argument has the wrong number of dimensions.
+	LengthError  ErrorKind = "LENGTH" // Arguments have dimensions that do not conform.
+	IndexError   ErrorKind = "INDEX"  // A subscript or axis is out of range.
+	SyntaxError  ErrorKind = "SYNTAX" // The source cannot be parsed.
+	ValueError   ErrorKind = "VALUE"  // A name has no value or meaning.
+
+	InterruptError ErrorKind = "INTERRUPT" // Evaluation was stopped, by the user or by a limit.
+	WsFullError    ErrorKind = "WS FULL"   // An array is larger than the limits allow.
+)
+
+// LivyError is what the interpreter panics with, when evaluation fails.
+// Top level callers recover it and return it as an error.
+type LivyError struct {
+	Kind   ErrorKind
+	Msg    string
+	Pos    int      // Byte offset of the offending token in Source, or -1 if not known.
+	Source string   // Source text that Pos refers to.
+	Calls  []string // Names of the user functions being executed, innermost first.
+}
+
+func (e *LivyError) Error() string {
+	if e.Kind == UnknownError {
+		return "ERROR: " + e.Msg
+	}
+	return fmt.Sprintf("%s ERROR: %s", e.Kind, e.Msg)
+}
+
+// Describe is a one-line description of the error, including its position if known.
+func (e *LivyError) Describe() string {
+	if e.Pos < 0 {
+		return e.Error()
+	}
+	return fmt.Sprintf("%s (at position %d)", e.Error(), e.Pos)
+}
+
+// Caret returns the line of source containing the error,
+// with a caret under the offending token.
+// It is empty if the position is not known.
+func (e *LivyError) Caret() string {
+	if e.Pos < 0 || e.Pos > len(e.Source) {
+		return ""
+	}
+	start := strings.LastIndex(e.Source[:e.Pos], "\n") + 1
+	end := strings.Index(e.Source[e.Pos:], "\n")
+	if end < 0 {
+		end = len(e.Source)
+	} else {
+		end += e.Pos
+	}
+
+	var bb strings.Builder
+	if start > 0 || end < len(e.Source) {
+		fmt.Fprintf(&bb, "at line %d:\n", 1+strings.Count(e.Source[:start], "\n"))
+	}
+	fmt.Fprintf(&bb, "      %s\n      ", e.Source[start:end])
+	for _, r := range e.Source[start:e.Pos] {
+		if r == '\t' {
+			bb.WriteRune('\t')
+		} else {
+			bb.WriteRune(' ')
+		}
+	}
+	bb.WriteString("^")
+	return bb.String()
+}
+
+// maxReportCalls is how many lines of calls Report shows, half from each end of the chain.
+const maxReportCalls = 20
+
+// Report describes the error on several lines:
+// the message, the source with a caret, and the chain of user function calls.
+// Repeated calls of the same function are shown once, with a count,
+// and a long chain shows only its ends.
+func (e *LivyError) Report() string {
+	lines := []string{e.Error()}
+	if caret := e.Caret(); caret != "" {
+		lines = append(lines, caret)
+	}
+	var calls []string
+	for i := 0; i < len(e.Calls); {
+		j := i + 1
+		for j < len(e.Calls) && e.Calls[j] == e.Calls[i] {
+			j++
+		}
+		if j-i > 1 {
+			calls = append(calls, fmt.Sprintf("  in %s (%d times)", e.Calls[i], j-i))
+		} else {
+			calls = append(calls, "  in "+e.Calls[i])
+		}
+		i = j
+	}
+	if n := len(calls); n > maxReportCalls {
+		half := maxReportCalls / 2
+		omitted := fmt.Sprintf("  ... %d more", n-maxReportCalls)
+		calls = append(append(calls[:half:half], omitted), calls[n-half:]...)
+	}
+	lines = append(lines, calls...)
+	return strings.Join(lines, "\n")
+}
+
+// ReportError describes any error, in detail if it is a *LivyError.
+func ReportError(err error) string {
+	if e, ok := err.(*LivyError); ok {
+		return e.Report()
+	}
+	return err.Error()
+}
+
+// Panicf raises a LivyError of the given kind.
+// Its position is filled in as it propagates through the evaluator.
+func Panicf(kind ErrorKind, format string, args ...interface{}) {
+	PanicAtf(kind, -1, format, args...)
+}
+
+// PanicAtf raises a LivyError of the given kind, blaming the token at the position.
+func PanicAtf(kind ErrorKind, pos int, format string, args ...interface{}) {
+	e := &LivyError{Kind: kind, Msg: fmt.Sprintf(format, args...), Pos: pos}
+	Log.Print(e)
+	panic(e)
+}
+
+// panicSourcef raises a LivyError of the given kind, at a position in the source text.
+func panicSourcef(kind ErrorKind, source string, pos int, format string, args ...interface{}) {
+	e := &LivyError{Kind: kind, Msg: fmt.Sprintf(format, args...), Pos: pos, Source: source}
+	Log.Print(e)
+	panic(e)
+}
+
+// AsLivyError converts a recovered panic value into a *LivyError.
+// It returns nil if there was no panic, or for break and continue,
+// which are not errors and must pass through.
+func AsLivyError(r interface{}) *LivyError {
+	switch x := r.(type) {
+	case nil, Break, Continue:
+		return nil
+	case *LivyError:
+		return x
+	case error:
+		return &LivyError{Msg: x.Error(), Pos: -1}
+	}
+	return &LivyError{Msg: fmt.Sprintf("%v", r), Pos: -1}
+}
+
+// ErrorFromPanic converts a value recovered at top level into a *LivyError,
+// giving it the source text if it does not know its source yet.
+func ErrorFromPanic(r interface{}, source string) *LivyError {
+	var e *LivyError
+	switch r.(type) {
+	case Break:
+		e = &LivyError{Kind: SyntaxError, Msg: "break outside of while loop", Pos: -1}
+	case Continue:
+		e = &LivyError{Kind: SyntaxError, Msg: "continue outside of while loop", Pos: -1}
+	default:
+		e = AsLivyError(r)
+	}
+	if e == nil {
+		return nil
+	}
+	if e.Source == "" && e.Pos >= 0 {
+		e.Source = source
+	}
+	return e
+}
+
+// blameToken is deferred by an expression, so an error from within it
+// that does not know its position yet gets the position of the token.
+func blameToken(t *Token) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	if e := AsLivyError(r); e != nil {
+		if e.Pos < 0 && t != nil {
+			e.Pos = t.Pos
+		}
+		panic(e)
+	}
+	panic(r)
+}
@@ -0,0 +1,79 @@
+package livy
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLivyErrorKinds(t *testing.T) {
+	tests := []struct {
+		src  string
+		kind ErrorKind
+	}{
+		{`1 2 3 + 4 5`, LengthError},
+		{`(2 2 rho 1) , 1 2 3`, RankError},
+		{`(2 3 rho 1) + 3 2 rho 1`, LengthError},
+		{`(2 3 rho 1) == 6 rho 1`, RankError},
+		{`1 2 3 {X + Y}~ 4 5`, LengthError},
+		{`(iota 3)[5]`, IndexError},
+		{`1 + (2`, SyntaxError},
+		{`Nope + 1`, ValueError},
+		{`nope 1`, ValueError},
+		{`"abc" + 1`, DomainError},
+		{`{ X * Y }/ iota 0`, DomainError},
+	}
+	for _, test := range tests {
+		c := NewContext()
+		_, err := c.EvalString(test.src)
+		e, ok := err.(*LivyError)
+		if !ok {
+			t.Errorf("For %q, got %v, wanted a *LivyError", test.src, err)
+			continue
+		}
+		if e.Kind != test.kind {
+			t.Errorf("For %q, got kind %q (%v), wanted %q", test.src, e.Kind, e, test.kind)
+		}
+	}
+}
+
+func TestLivyErrorCaretAndCalls(t *testing.T) {
+	c := NewContext()
+	_, err := c.EvalString(`def inner Y { Y + Nope } ; def outer Y { 1 + inner Y }`)
+	if err != nil {
+		t.Fatalf("def: %v", err)
+	}
+	_, err = c.EvalString(`outer 5`)
+	e, ok := err.(*LivyError)
+	if !ok {
+		t.Fatalf("Got %v, wanted a *LivyError", err)
+	}
+	if e.Kind != ValueError {
+		t.Errorf("Got kind %q, wanted VALUE", e.Kind)
+	}
+	if got := strings.Join(e.Calls, " "); got != "inner outer" {
+		t.Errorf("Got calls %q, wanted %q", got, "inner outer")
+	}
+	want := "      def inner Y { Y + Nope }\n                        ^"
+	if got := e.Caret(); got != want {
+		t.Errorf("Got caret\n%s\nwanted\n%s", got, want)
+	}
+	if len(c.Frames) != 0 {
+		t.Errorf("Frames left after error: %d", len(c.Frames))
+	}
+}
+
+func TestReportLongCallChains(t *testing.T) {
+	c := NewContext()
+	c.MaxDepth = 100
+	_, err := c.EvalString(`def f Y { f Y } ; f 1`)
+	got := ReportError(err)
+	if !strings.HasSuffix(got, "\n  in f (100 times)") || strings.Count(got, "  in ") != 1 {
+		t.Errorf("Got report\n%s", got)
+	}
+
+	_, err = c.EvalString(`def g Y { h Y } ; def h Y { g Y } ; g 1`)
+	got = ReportError(err)
+	if strings.Count(got, "  in ") != maxReportCalls || !strings.Contains(got, "\n  ... 80 more\n") {
+		t.Errorf("Got report\n%s", got)
+	}
+}
@@ -2,7 +2,6 @@ package livy
 
 import (
 	"fmt"
-	"log"
 	"runtime/debug"
 )
 
@@ -16,7 +15,8 @@ type Expression interface {
 }
 
 type Variable struct {
-	S string
+	S   string
+	Pos int // Position in source, for errors.
 }
 
 type Number struct {
@@ -32,6 +32,7 @@ type Monad struct {
 	Op    string
 	B     Expression
 	Axis  Expression
+	Fn    FuncExpression
 }
 
 type Dyad struct {
@@ -40,6 +41,7 @@ type Dyad struct {
 	Op    string
 	B     Expression
 	Axis  Expression
+	Fn    FuncExpression
 }
 
 type List struct {
@@ -57,6 +59,13 @@ type Def struct {
 	Axis   string
 	Rhs    string
 	Locals []string
+	Source string // Original text of the def, for saving workspaces.
+	Start  int    // Offset of Source in the text it was parsed from.
+
+	// Operand names, if this defines an operator like `def (f power N) Y`.
+	// Operands named in lowercase are functions; uppercase are values.
+	LeftOperand  string
+	RightOperand string
 }
 
 type Cond struct {
@@ -70,15 +79,24 @@ type While struct {
 	Do    *Seq
 }
 
+// Try evaluates the Try sequence, and if it fails with an error,
+// binds a description of the error to Var (if named) and evaluates Catch.
+// An INTERRUPT ERROR is not caught, so a loop of try cannot be made unstoppable.
+type Try struct {
+	Try   *Seq
+	Var   string
+	Catch *Seq
+}
+
 type Subscript struct {
 	Matrix Expression
 	Vec    []Expression
 }
 
 func (o Variable) Eval(c *Context) Val {
-	z, ok := c.Globals[o.S]
+	z, ok := c.GetVar(o.S)
 	if !ok {
-		Log.Panicf("No such variable %q in Globals %#v", o.S, c.Globals)
+		PanicAtf(ValueError, o.Pos, "No such variable %q", o.S)
 	}
 	return z
 }
@@ -89,50 +107,14 @@ func (o Literal) Eval(c *Context) Val {
 	return o.V
 }
 func (o Monad) Eval(c *Context) Val {
-	var ok bool
-	var fn MonadicFunc
-	switch o.Token.Type {
-	case OperatorToken:
-		fn, ok = c.Monadics[o.Op]
-		if !ok {
-			Log.Panicf("No such monadaic operator %q", o.Op)
-		}
-	case ReduceToken:
-		op1 := o.Token.Match[1]
-		fn1, ok := c.Dyadics[op1]
-		if !ok {
-			Log.Panicf("Reduce syntax: No such dyadaic operator %q", op1)
-		}
-		identity, ok := IdentityValueOfDyadic[op1]
-		if !ok {
-			identity = Zero
-		}
-		fn = MkReduceOrScanOp(o.Token.Str, fn1, identity, false)
-	case ScanToken:
-		op1 := o.Token.Match[1]
-		fn1, ok := c.Dyadics[op1]
-		if !ok {
-			Log.Panicf("Scan syntax: No such dyadaic operator %q", op1)
-		}
-		identity, ok := IdentityValueOfDyadic[op1]
-		if !ok {
-			identity = Zero
-		}
-		fn = MkReduceOrScanOp(o.Token.Str, fn1, identity, true)
-	case EachToken:
-		op1 := o.Token.Match[1]
-		fn1, ok := c.Monadics[op1]
-		if !ok {
-			Log.Panicf("Each syntax: No such dyadaic operator %q", op1)
-		}
-		fn = MkEachOpMonadic(o.Token.Str, fn1)
-	}
+	defer blameToken(o.Token)
+	fn := o.Fn.EvalFunc(c).GetMonadic()
 
 	b := EvalFor(c, o.B, "RHS of Monadic expression", o.Op)
 	Log.Printf("Monad:Eval %s %s -> ?", o.Op, b)
 	axis := DefaultAxis
 	if o.Axis != nil {
-		axis = EvalFor(c, o.Axis, "Axis of Monadic expression", o.Op).GetScalarInt()
+		axis = c.ScalarInt(EvalFor(c, o.Axis, "Axis of Monadic expression", o.Op))
 	}
 	z := CallFor(c, func() Val { return fn(c, b, axis) }, "evaluation of Monadic function", o.Op)
 	Log.Printf("Monad:Eval %s %s -> %s", o.Op, b, z)
@@ -146,61 +128,26 @@ func (o Dyad) Assign(c *Context) Val {
 		return t.Assign(c, b)
 
 	case *Variable:
-		c.Globals[t.S] = b
+		c.SetVar(t.S, b)
 		Log.Printf("Assigning %s = %s", t.S, b)
 		return b
 
 	default:
-		Log.Panicf("cannot assign to %s", o.A)
+		Panicf(SyntaxError, "cannot assign to %s", o.A)
 		panic(0)
 	}
 }
 func (o Dyad) Eval(c *Context) Val {
+	defer blameToken(o.Token)
 	if o.Op == "=" {
 		return o.Assign(c)
 	}
-	var fn DyadicFunc
-	switch o.Token.Type {
-	default:
-		Log.Panicf("Default case: token %v", o.Token.Type)
-	case OperatorToken:
-		fn1, ok := c.Dyadics[o.Op]
-		if !ok {
-			Log.Panicf("No such dyadaic operator %q", o.Op)
-		}
-		fn = fn1
-	case EachToken:
-		op1 := o.Token.Match[1]
-		fn1, ok := c.Dyadics[op1]
-		if !ok {
-			Log.Panicf("Each syntax: No such dyadaic operator %q", op1)
-		}
-		fn = MkEachOpDyadic(o.Token.Str, fn1)
-	case InnerProductToken:
-		op1 := o.Token.Match[1]
-		fn1, ok := c.Dyadics[op1]
-		if !ok {
-			Log.Panicf("Inner product syntax: No such dyadaic operator %q", op1)
-		}
-		op2 := o.Token.Match[2]
-		fn2, ok := c.Dyadics[op2]
-		if !ok {
-			Log.Panicf("Inner product syntax: No such dyadaic operator %q", op2)
-		}
-		fn = MkInnerProduct(o.Token.Str, fn1, fn2)
-	case OuterProductToken:
-		op1 := o.Token.Match[1]
-		fn1, ok := c.Dyadics[op1]
-		if !ok {
-			Log.Panicf("Outer product syntax: No such dyadaic operator %q", op1)
-		}
-		fn = MkOuterProduct(o.Token.Str, fn1)
-	}
+	fn := o.Fn.EvalFunc(c).GetDyadic()
 
 	b := EvalFor(c, o.B, "RHS of Dyadic expression", o.Op)
 	axis := DefaultAxis
 	if o.Axis != nil {
-		axis = EvalFor(c, o.Axis, "Axis of Dyadic expression", o.Op).GetScalarInt()
+		axis = c.ScalarInt(EvalFor(c, o.Axis, "Axis of Dyadic expression", o.Op))
 	}
 	a := EvalFor(c, o.A, "LHS of Dyadic expression", o.Op)
 
@@ -242,9 +189,38 @@ func (Break) Eval(c *Context) Val {
 func (Continue) Eval(c *Context) Val {
 	panic(CONTINUE)
 }
+func (o Try) Eval(c *Context) Val {
+	var caught *LivyError
+	z := func() Val {
+		defer func() {
+			r := recover()
+			if r == nil {
+				return
+			}
+			caught = AsLivyError(r)
+			if caught == nil {
+				panic(r) // Break and Continue pass through.
+			}
+			if caught.Kind == InterruptError {
+				panic(caught) // So does stopping evaluation.
+			}
+		}()
+		return o.Try.Eval(c)
+	}()
+	if caught == nil {
+		return z
+	}
+
+	if o.Var != "" {
+		c.SetVar(o.Var, StringMat(caught.Describe()))
+	}
+	return o.Catch.Eval(c)
+}
+
 func (o While) Eval(c *Context) Val {
 	var z []Val
 	for {
+		c.Step()
 		cond := o.While.Eval(c)
 		b := float2bool(cond.GetScalarFloat())
 		if !b {
@@ -276,44 +252,36 @@ func (o While) Eval(c *Context) Val {
 			z = append(z, item)
 		}
 	}
-	return &Mat{z, []int{len(z)}}
+	return &Mat{M: z, S: []int{len(z)}}
+}
+
+// Call runs the def with the given arguments,
+// and the given operands if it defines an operator.
+// The arguments, operands, and locals live in a new Frame, made by callUser,
+// so they shadow globals and operators of the same names only during the call.
+func (o Def) Call(c *Context, a Val, b Val, axis int, funcs map[string]*Function, operands map[string]Val) Val {
+	args := map[string]Val{o.Rhs: b}
+	if o.Lhs != "" {
+		args[o.Lhs] = a
+	}
+	if o.Axis != "" {
+		args[o.Axis] = &Num{complex(float64(axis), 0)}
+	}
+	for k, v := range operands {
+		args[k] = v
+	}
+	frame := &Frame{Name: o.Name, Funcs: funcs, Source: o.Source, Start: o.Start}
+	return callUser(c, frame, o.Locals, args, o.Seq)
 }
 
 func (o Def) Eval(c *Context) Val {
-	fn := func(c *Context, a Val, b Val, axis int) Val {
-		// We do the stupid thing where all variables
-		// are used from c.Globals context, but we save and
-		// restore global variables shadowed by local
-		// variables on entry and exit to functions.
-		localMap := make(map[string]Val)
-		for _, lvar := range o.Locals {
-			gval, _ := c.Globals[lvar]
-			localMap[lvar] = gval
-			c.Globals[lvar] = &Num{0}
-		}
-		c.LocalStack = append(c.LocalStack, localMap)
-		defer func() {
-			n_1 := len(c.LocalStack) - 1
-			localMap = c.LocalStack[n_1]
-			c.LocalStack = c.LocalStack[:n_1]
-			for _, lvar := range o.Locals {
-				saved := localMap[lvar]
-				if saved == nil {
-					delete(c.Globals, lvar)
-				} else {
-					c.Globals[lvar] = saved
-				}
-			}
-		}()
+	if o.LeftOperand != "" {
+		c.DefineOperator(&o)
+		return &Box{"def"}
+	}
 
-		if o.Lhs != "" {
-			c.Globals[o.Lhs] = a
-		}
-		if o.Axis != "" {
-			c.Globals[o.Axis] = &Num{complex(float64(axis), 0)}
-		}
-		c.Globals[o.Rhs] = b
-		return o.Seq.Eval(c)
+	fn := func(c *Context, a Val, b Val, axis int) Val {
+		return o.Call(c, a, b, axis, nil, nil)
 	}
 
 	if o.Lhs == "" {
@@ -325,6 +293,7 @@ func (o Def) Eval(c *Context) Val {
 			return fn(c, a, b, axis)
 		}
 	}
+	c.RememberDef(o.Lhs != "", o.Name, o.Source)
 	return &Box{"def"}
 }
 
@@ -344,11 +313,11 @@ func (o Subscript) PreEval(c *Context) (mat *Mat, newShape []int, subscripts [][
 	lhs := o.Matrix.Eval(c)
 	mat, ok = lhs.(*Mat)
 	if !ok {
-		Log.Panicf("Cannot subscript non-matrix: %s", lhs)
+		Panicf(RankError, "Cannot subscript non-matrix: %s", lhs)
 	}
 	rank := len(mat.S)
 	if len(o.Vec) != rank {
-		Log.Panicf("Number of subscripts %d does not match rank %d of matrix: %s", len(o.Vec), rank, lhs)
+		Panicf(RankError, "Number of subscripts %d does not match rank %d of matrix: %s", len(o.Vec), rank, lhs)
 	}
 
 	for i, sub := range o.Vec {
@@ -359,50 +328,46 @@ func (o Subscript) PreEval(c *Context) (mat *Mat, newShape []int, subscripts [][
 		} else {
 			r := sub.Eval(c).Ravel()
 			newShape = append(newShape, len(r))
-			ints := make([]int, len(r))
-			for i, e := range r {
-				ints[i] = e.GetScalarInt()
-			}
-			subscripts = append(subscripts, ints)
+			subscripts = append(subscripts, subscriptInts(c, r, mat.S[i]))
 		}
 	}
 	return mat, newShape, subscripts
 }
 func (o Subscript) Eval(c *Context) Val {
 	mat, newShape, subscripts := o.PreEval(c)
-	newSize := Product(newShape)
+	newSize := c.AllocShape(newShape)
 	newMat := &Mat{M: make([]Val, newSize), S: newShape}
 	if len(newShape) > 0 {
-		copyIntoSubscriptedMatrix(newShape, subscripts, 0, mat, mat.S, newMat.M, 0)
+		copyIntoSubscriptedMatrix(newShape, subscripts, 0, mat, mat.S, newMat.Vals(), 0)
 	}
 	return newMat
 }
 func (o Subscript) Assign(c *Context, b Val) Val {
 	bmat, ok := b.(*Mat)
 	if !ok {
-		Log.Panicf("Cannot assign non-matrix to subscripted variable: %v", b)
+		Panicf(RankError, "Cannot assign non-matrix to subscripted variable: %v", b)
 	}
 
 	avar, ok := o.Matrix.(*Variable)
 	if !ok {
-		Log.Panicf("Cannot assign to subscripted non-variable: %s", o.Matrix)
+		Panicf(SyntaxError, "Cannot assign to subscripted non-variable: %s", o.Matrix)
 	}
 
 	aval := avar.Eval(c)
 	amat, ok := aval.(*Mat)
 	if !ok {
-		Log.Panicf("Cannot assign to subscripted non-matrix variable: %s", amat)
+		Panicf(RankError, "Cannot assign to subscripted non-matrix variable: %s", amat)
 	}
 
 	rank := len(amat.S)
 	if len(o.Vec) != rank {
-		Log.Panicf("Number of subscripts %d does not match rank %d of matrix: %s", len(o.Vec), rank, aval)
+		Panicf(RankError, "Number of subscripts %d does not match rank %d of matrix: %s", len(o.Vec), rank, aval)
 	}
 
 	// Replace mat with a copy, that can be modified.
-	matM := make([]Val, len(amat.M)) // Alloc new contents.
-	copy(matM, amat.M)               // Copy the contents.
-	mat := &Mat{matM, amat.S}        // New mat with newly copied contents.  Shape is immutable and can be shared.
+	matM := make([]Val, amat.Len()) // Alloc new contents.
+	copy(matM, amat.Vals())         // Copy the contents.
+	mat := &Mat{M: matM, S: amat.S} // New mat with newly copied contents.  Shape is immutable and can be shared.
 
 	var subscripts [][]int
 	for i, sub := range o.Vec {
@@ -411,11 +376,7 @@ func (o Subscript) Assign(c *Context, b Val) Val {
 			subscripts = append(subscripts, intRange(mat.S[i]))
 		} else {
 			r := EvalFor(c, sub, "subscripts in subscripted assignment of variable", "").Ravel()
-			ints := make([]int, len(r))
-			for i, e := range r {
-				ints[i] = e.GetScalarInt()
-			}
-			subscripts = append(subscripts, ints)
+			subscripts = append(subscripts, subscriptInts(c, r, mat.S[i]))
 		}
 	}
 
@@ -424,7 +385,7 @@ func (o Subscript) Assign(c *Context, b Val) Val {
 	recurse = func(subscripts [][]int, newShape []int, newOff int) {
 		Log.Printf("subscripts=%v newShape=%v newOff=%d oldOff=%d", subscripts, newShape, newOff, oldOff)
 		if len(newShape) == 0 {
-			matM[newOff] = bmat.M[oldOff]
+			matM[newOff] = bmat.Vals()[oldOff]
 			oldOff++
 			return
 		}
@@ -435,17 +396,31 @@ func (o Subscript) Assign(c *Context, b Val) Val {
 
 	}
 	recurse(subscripts, amat.S, 0)
-	c.Globals[avar.S] = mat
+	c.SetVar(avar.S, mat)
 	return b
 }
 
+// subscriptInts converts the values of one subscript to ints,
+// checking that each is in range for the dimension.
+func subscriptInts(c *Context, r []Val, dim int) []int {
+	ints := make([]int, len(r))
+	for i, e := range r {
+		x := c.ScalarInt(e)
+		if x < 0 || x >= dim {
+			Panicf(IndexError, "subscript %d out of range for dimension %d", x, dim)
+		}
+		ints[i] = x
+	}
+	return ints
+}
+
 func copyIntoSubscriptedMatrix(shape []int, subscripts [][]int, subOffset int, mat *Mat, matShape []int, z []Val, offset int) {
 	if shape[0] == 0 {
 		return
 	}
 	if len(shape) == 1 {
 		for i := 0; i < shape[0]; i++ {
-			z[offset+i] = mat.M[subOffset+subscripts[0][i]]
+			z[offset+i] = mat.Vals()[subOffset+subscripts[0][i]]
 		}
 	} else {
 		for i := 0; i < shape[0]; i++ {
@@ -512,6 +487,10 @@ func (o Cond) String() string {
 	return fmt.Sprintf("Cond(if %v then %v else %v fi)", o.If, o.Then, o.Else)
 }
 
+func (o Try) String() string {
+	return fmt.Sprintf("Try(try %v catch %s %v end)", o.Try, o.Var, o.Catch)
+}
+
 func (o While) String() string {
 	return fmt.Sprintf("While(while %v do %v done)", o.While, o.Do)
 }
@@ -539,14 +518,14 @@ func float2bool(f float64) bool {
 	if f == 0.0 {
 		return false
 	}
-	Log.Panicf("Cannot use %.18g as a bool", f)
+	Panicf(DomainError, "Cannot use %.18g as a bool", f)
 	panic(0)
 }
 
 func cx2bool(c complex128) bool {
 	re, im := real(c), imag(c)
 	if im != 0 {
-		Log.Panicf("Cannot use %s as a bool", Cx2Str(c))
+		Panicf(DomainError, "Cannot use %s as a bool", Cx2Str(c))
 	}
 	if re == 1.0 {
 		return true
@@ -554,7 +533,7 @@ func cx2bool(c complex128) bool {
 	if re == 0.0 {
 		return false
 	}
-	Log.Panicf("Cannot use %s as a bool", Cx2Str(c))
+	Panicf(DomainError, "Cannot use %s as a bool", Cx2Str(c))
 	panic(0)
 }
 
@@ -562,7 +541,7 @@ func EvalFor(c *Context, expr Expression, why string, what string) Val {
 	defer func() {
 		r := recover()
 		if r != nil {
-			log.Printf("... during: %s: %s", why, what)
+			Log.Printf("... during: %s: %s", why, what)
 			panic(r)
 		}
 	}()
@@ -573,7 +552,7 @@ func CallFor(c *Context, fn func() Val, why string, what string) Val {
 	defer func() {
 		r := recover()
 		if r != nil {
-			log.Printf("... during: %s: %s", why, what)
+			Log.Printf("... during: %s: %s", why, what)
 			panic(r)
 		}
 	}()
@@ -0,0 +1,428 @@
+package livy
+
+import (
+	"math"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Exact is an exact rational number, for arithmetic that must not round.
+// Integers are kept as int64 while they fit, then as big.Int.
+// Exact numbers come from literals like `30x` or `1r3`, or from `exact`,
+// and +, -, *, / and friends keep them exact when both sides are exact,
+// or one side is exact and the other is an integer.
+type Exact struct {
+	I int64    // The value, if B and R are nil.
+	B *big.Int // The value, if it is an integer too large for int64.
+	R *big.Rat // The value, if it is not an integer.
+}
+
+// MaxExactBits limits the numerator and denominator of exact results from * and **,
+// which could otherwise grow too large to compute in any reasonable time.
+// Larger results are a WS FULL ERROR.
+var MaxExactBits = 1 << 20
+
+// checkExactBits raises a WS FULL ERROR if a result could have too many bits.
+func checkExactBits(bits int64) {
+	if bits > int64(MaxExactBits) {
+		Panicf(WsFullError, "exact result of about %d bits is larger than the limit of %d", bits, MaxExactBits)
+	}
+}
+
+// ExactInt makes an exact integer.
+func ExactInt(i int64) *Exact {
+	return &Exact{I: i}
+}
+
+// ExactRat makes an exact number of the rational, in its narrowest form.
+func ExactRat(r *big.Rat) *Exact {
+	if !r.IsInt() {
+		return &Exact{R: r}
+	}
+	return ExactBig(r.Num())
+}
+
+// ExactBig makes an exact integer of the big.Int, in its narrowest form.
+func ExactBig(b *big.Int) *Exact {
+	if b.IsInt64() {
+		return &Exact{I: b.Int64()}
+	}
+	return &Exact{B: new(big.Int).Set(b)}
+}
+
+var matchExactLiteral = regexp.MustCompile(`^([-+]?[0-9]+)(x|r([0-9]+))$`).FindStringSubmatch
+
+// ParseExact parses an exact literal:  an integer with suffix `x`, like `30x`,
+// or a rational with `r` between numerator and denominator, like `1r3`.
+func ParseExact(s string) (*Exact, bool) {
+	m := matchExactLiteral(s)
+	if m == nil {
+		return nil, false
+	}
+	num, ok := new(big.Int).SetString(strings.TrimPrefix(m[1], "+"), 10)
+	if !ok {
+		return nil, false
+	}
+	if m[2] == "x" {
+		return ExactBig(num), true
+	}
+	den, ok := new(big.Int).SetString(m[3], 10)
+	if !ok || den.Sign() == 0 {
+		return nil, false
+	}
+	return ExactRat(new(big.Rat).SetFrac(num, den)), true
+}
+
+// Rat returns the value as a new big.Rat.
+func (o Exact) Rat() *big.Rat {
+	switch {
+	case o.R != nil:
+		return new(big.Rat).Set(o.R)
+	case o.B != nil:
+		return new(big.Rat).SetInt(o.B)
+	}
+	return new(big.Rat).SetInt64(o.I)
+}
+
+// IsInt tells if the value is an integer.
+func (o Exact) IsInt() bool {
+	return o.R == nil
+}
+
+// small tells if the value is the int64 I.
+func (o Exact) small() bool {
+	return o.R == nil && o.B == nil
+}
+
+// Cmp is -1, 0, or 1 as o is less than, equal to, or greater than x.
+func (o Exact) Cmp(x Exact) int {
+	if o.small() && x.small() {
+		switch {
+		case o.I < x.I:
+			return -1
+		case o.I > x.I:
+			return 1
+		}
+		return 0
+	}
+	return o.Rat().Cmp(x.Rat())
+}
+
+// Sign is -1, 0, or 1 as o is negative, zero, or positive.
+func (o Exact) Sign() int {
+	switch {
+	case o.R != nil:
+		return o.R.Sign()
+	case o.B != nil:
+		return o.B.Sign()
+	case o.I < 0:
+		return -1
+	case o.I > 0:
+		return 1
+	}
+	return 0
+}
+
+// Float64 is the nearest float64, and whether it is exactly the value.
+func (o Exact) Float64() (float64, bool) {
+	switch {
+	case o.R != nil:
+		return o.R.Float64()
+	case o.B != nil:
+		f, acc := new(big.Float).SetInt(o.B).Float64()
+		return f, acc == big.Exact
+	}
+	f := float64(o.I)
+	return f, f < 1<<63 && int64(f) == o.I
+}
+
+func (o Exact) text() string {
+	switch {
+	case o.R != nil:
+		return strings.Replace(o.R.String(), "/", "r", 1)
+	case o.B != nil:
+		return o.B.String()
+	}
+	return strconv.FormatInt(o.I, 10)
+}
+
+// String marks exact integers with `x`, so they read back as exact.
+func (o Exact) String() string {
+	if o.IsInt() {
+		return o.text() + "x "
+	}
+	return o.text() + " "
+}
+func (o Exact) Pretty() string {
+	return o.text() + "  "
+}
+
+// Compare orders exact numbers by value, and among other numbers
+// like Num.Compare, by real part, then by imaginary part.
+func (a Exact) Compare(x Val) int {
+	if b, ok := asExact(x); ok {
+		return a.Cmp(b)
+	}
+	if b, ok := exactOfCx(x.GetScalarCx(), false); ok {
+		return a.Cmp(b)
+	}
+	f, _ := a.Float64()
+	return Num{complex(f, 0)}.Compare(x)
+}
+
+func (o Exact) ValEnum() ValEnum {
+	return NumVal
+}
+func (o Exact) Size() int {
+	return 1
+}
+func (o Exact) Shape() []int {
+	return nil
+}
+func (o Exact) Ravel() []Val {
+	return []Val{o}
+}
+func (o Exact) GetScalarOrNil() Val {
+	return o
+}
+func (o Exact) GetScalarInt() int {
+	if o.small() && int64(int(o.I)) == o.I {
+		return int(o.I)
+	}
+	if !o.IsInt() {
+		Panicf(DomainError, "Not an integer: %s", o.text())
+	}
+	Panicf(DomainError, "Integer too large: %s", o.text())
+	panic(0)
+}
+func (o Exact) GetScalarFloat() float64 {
+	f, _ := o.Float64()
+	return f
+}
+func (o Exact) GetScalarCx() complex128 {
+	return complex(o.GetScalarFloat(), 0)
+}
+
+// asExact returns the value if it is an Exact number.
+func asExact(v Val) (Exact, bool) {
+	switch t := v.(type) {
+	case *Exact:
+		return *t, true
+	case Exact:
+		return t, true
+	}
+	return Exact{}, false
+}
+
+// exactOfCx converts a real number to Exact, if it is an integer,
+// or if fraction is true, any finite real.
+func exactOfCx(x complex128, fraction bool) (Exact, bool) {
+	re := real(x)
+	if imag(x) != 0 || math.IsInf(re, 0) || math.IsNaN(re) {
+		return Exact{}, false
+	}
+	if re == math.Trunc(re) && math.Abs(re) < math.MaxInt64 {
+		return Exact{I: int64(re)}, true
+	}
+	if re != math.Trunc(re) && !fraction {
+		return Exact{}, false
+	}
+	return *ExactRat(new(big.Rat).SetFloat64(re)), true
+}
+
+// exactPair converts a pair of numbers for exact arithmetic:
+// both must be Exact, or one Exact and the other an integer.
+func exactPair(a, b Val) (x, y Exact, ok bool) {
+	x, xok := asExact(a)
+	y, yok := asExact(b)
+	switch {
+	case xok && yok:
+		return x, y, true
+	case xok && b.ValEnum() == NumVal:
+		y, ok = exactOfCx(b.GetScalarCx(), false)
+		return x, y, ok
+	case yok && a.ValEnum() == NumVal:
+		x, ok = exactOfCx(a.GetScalarCx(), false)
+		return x, y, ok
+	}
+	return x, y, false
+}
+
+// FuncExactExactVal is the exact form of a dyadic scalar function.
+// It returns nil if the result cannot be exact, to use the inexact form.
+type FuncExactExactVal func(a, b Exact) Val
+
+// FuncExactVal is the exact form of a monadic scalar function.
+// It returns nil if the result cannot be exact, to use the inexact form.
+type FuncExactVal func(b Exact) Val
+
+// WrapExactDyadic tries the exact form of a scalar function before the inexact fn.
+func WrapExactDyadic(exact FuncExactExactVal, fn DyadicFunc) DyadicFunc {
+	return func(c *Context, a, b Val, axis int) Val {
+		if x, y, ok := exactPair(a, b); ok {
+			if z := exact(x, y); z != nil {
+				return z
+			}
+		}
+		return fn(c, a, b, axis)
+	}
+}
+
+// WrapExactMonadic tries the exact form of a scalar function before the inexact fn.
+func WrapExactMonadic(exact FuncExactVal, fn MonadicFunc) MonadicFunc {
+	return func(c *Context, b Val, axis int) Val {
+		if y, ok := asExact(b); ok {
+			if z := exact(y); z != nil {
+				return z
+			}
+		}
+		return fn(c, b, axis)
+	}
+}
+
+func exactAdd(a, b Exact) Val {
+	if a.small() && b.small() {
+		if s := a.I + b.I; (a.I^s)&(b.I^s) >= 0 {
+			return ExactInt(s)
+		}
+	}
+	return ExactRat(new(big.Rat).Add(a.Rat(), b.Rat()))
+}
+
+func exactSub(a, b Exact) Val {
+	if a.small() && b.small() {
+		if d := a.I - b.I; (a.I^b.I)&(a.I^d) >= 0 {
+			return ExactInt(d)
+		}
+	}
+	return ExactRat(new(big.Rat).Sub(a.Rat(), b.Rat()))
+}
+
+func exactMul(a, b Exact) Val {
+	if a.small() && b.small() {
+		if a.I == 0 || b.I == 0 {
+			return ExactInt(0)
+		}
+		p := a.I * b.I
+		if p/b.I == a.I && !(a.I == -1 && b.I == math.MinInt64) && !(b.I == -1 && a.I == math.MinInt64) {
+			return ExactInt(p)
+		}
+	}
+	ra, rb := a.Rat(), b.Rat()
+	checkExactBits(int64(ra.Num().BitLen() + rb.Num().BitLen()))
+	checkExactBits(int64(ra.Denom().BitLen() + rb.Denom().BitLen()))
+	return ExactRat(new(big.Rat).Mul(ra, rb))
+}
+
+// exactDiv raises a DOMAIN ERROR for division by zero, which has no exact result.
+func exactDiv(a, b Exact) Val {
+	if b.Sign() == 0 {
+		Panicf(DomainError, "exact division by zero: %s / 0", a.text())
+	}
+	return ExactRat(new(big.Rat).Quo(a.Rat(), b.Rat()))
+}
+
+// exactPow raises to an integer power.  Other powers are inexact.
+// A negative power of zero is a DOMAIN ERROR, like division by zero.
+func exactPow(a, b Exact) Val {
+	if !b.small() {
+		return nil
+	}
+	if b.I < 0 {
+		if a.Sign() == 0 {
+			Panicf(DomainError, "exact division by zero: 0 ** %d", b.I)
+		}
+		a = *ExactRat(new(big.Rat).Inv(a.Rat()))
+		b.I = -b.I
+	}
+	r := a.Rat()
+	for _, x := range []*big.Int{r.Num(), r.Denom()} {
+		// Powers of 0, 1, and -1 stay small.
+		if n := int64(x.BitLen()); n > 1 && b.I > int64(MaxExactBits)/n {
+			Panicf(WsFullError, "exact power %s ** %d would have more than %d bits", a.text(), b.I, MaxExactBits)
+		}
+	}
+	e := big.NewInt(b.I)
+	num := new(big.Int).Exp(r.Num(), e, nil)
+	den := new(big.Int).Exp(r.Denom(), e, nil)
+	return ExactRat(new(big.Rat).SetFrac(num, den))
+}
+
+// exactMod is the remainder after truncated division, like math.Mod,
+// so it has the sign of a.  Modulo zero is inexact.
+func exactMod(a, b Exact) Val {
+	if b.Sign() == 0 {
+		return nil
+	}
+	if a.small() && b.small() && b.I != -1 {
+		return ExactInt(a.I % b.I)
+	}
+	q := new(big.Rat).Quo(a.Rat(), b.Rat())
+	t := new(big.Int).Quo(q.Num(), q.Denom())
+	r := new(big.Rat).Mul(b.Rat(), new(big.Rat).SetInt(t))
+	return ExactRat(r.Sub(a.Rat(), r))
+}
+
+func exactNeg(b Exact) Val {
+	if b.small() && b.I != math.MinInt64 {
+		return ExactInt(-b.I)
+	}
+	return ExactRat(new(big.Rat).Neg(b.Rat()))
+}
+
+func exactAbs(b Exact) Val {
+	if b.Sign() < 0 {
+		return exactNeg(b)
+	}
+	return &b
+}
+
+func exactSgn(b Exact) Val {
+	return ExactInt(int64(b.Sign()))
+}
+
+// exactRecip leaves the reciprocal of zero to the inexact form.
+func exactRecip(b Exact) Val {
+	return exactDiv(Exact{I: 1}, b)
+}
+
+func exactFloor(b Exact) Val {
+	if b.IsInt() {
+		return &b
+	}
+	q, m := new(big.Int), new(big.Int)
+	q.DivMod(b.R.Num(), b.R.Denom(), m) // Euclidean, and the denominator is positive.
+	return ExactBig(q)
+}
+
+func exactCeil(b Exact) Val {
+	if b.IsInt() {
+		return &b
+	}
+	floor := *exactFloor(b).(*Exact)
+	return exactAdd(floor, Exact{I: 1})
+}
+
+// monadicExact converts real numbers to Exact, exactly as stored.
+func monadicExact(c *Context, b Val, axis int) Val {
+	if y, ok := asExact(b); ok {
+		return &y
+	}
+	if b.ValEnum() == NumVal {
+		if y, ok := exactOfCx(b.GetScalarCx(), true); ok {
+			return &y
+		}
+	}
+	Panicf(DomainError, "exact wants finite real numbers, but got %s", b)
+	panic(0)
+}
+
+// monadicInexact converts Exact numbers to Num.
+func monadicInexact(c *Context, b Val, axis int) Val {
+	if b.ValEnum() != NumVal {
+		Panicf(DomainError, "inexact wants numbers, but got %s", b)
+	}
+	return &Num{b.GetScalarCx()}
+}
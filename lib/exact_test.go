@@ -0,0 +1,135 @@
+package livy
+
+import (
+	"bytes"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestExactOverflowPromotes(t *testing.T) {
+	max, min := Exact{I: math.MaxInt64}, Exact{I: math.MinInt64}
+	for _, tc := range []struct {
+		z    Val
+		want string
+	}{
+		{exactAdd(max, Exact{I: 1}), "9223372036854775808x "},
+		{exactSub(min, Exact{I: 1}), "-9223372036854775809x "},
+		{exactMul(max, Exact{I: 2}), "18446744073709551614x "},
+		{exactMul(min, Exact{I: -1}), "9223372036854775808x "},
+		{exactNeg(min), "9223372036854775808x "},
+		{exactSub(*exactAdd(max, Exact{I: 1}).(*Exact), Exact{I: 1}), "9223372036854775807x "},
+	} {
+		if got := tc.z.String(); got != tc.want {
+			t.Errorf("Got %s, wanted %s", got, tc.want)
+		}
+	}
+	// Back in range, the number is an int64 again.
+	if z := exactSub(*exactAdd(max, Exact{I: 1}).(*Exact), Exact{I: 1}).(*Exact); z.B != nil || z.I != math.MaxInt64 {
+		t.Errorf("Got %#v, wanted a small integer", z)
+	}
+}
+
+func TestExactGetScalarInt(t *testing.T) {
+	c := NewContext()
+	if got := c.ScalarInt(ExactInt(1 << 60)); got != 1<<60 {
+		t.Errorf("Got %d, wanted %d", got, 1<<60)
+	}
+	for _, src := range []string{`iota 2x ** 70`, `iota 1r2`} {
+		_, err := c.EvalString(src)
+		if e, ok := err.(*LivyError); !ok || e.Kind != DomainError {
+			t.Errorf("For %q, got %v, wanted a DOMAIN ERROR", src, err)
+		}
+	}
+}
+
+func TestParseExact(t *testing.T) {
+	for s, want := range map[string]string{
+		"7x":                              "7x ",
+		"-7x":                             "-7x ",
+		"6r4":                             "3r2 ",
+		"-6r3":                            "-2x ",
+		"123456789012345678901234567890x": "123456789012345678901234567890x ",
+	} {
+		z, ok := ParseExact(s)
+		if !ok || z.String() != want {
+			t.Errorf("ParseExact(%q) is %v, %v; wanted %s", s, z, ok, want)
+		}
+	}
+	for _, s := range []string{"7", "1r0", "1.5x", "x"} {
+		if _, ok := ParseExact(s); ok {
+			t.Errorf("ParseExact(%q) should fail", s)
+		}
+	}
+}
+
+func TestSaveLoadExact(t *testing.T) {
+	c := NewContext()
+	evalIn(c, `A = 1r3 , (2x ** 100) , 5x , 0.5`)
+
+	var bb bytes.Buffer
+	if err := c.SaveWorkspace(&bb); err != nil {
+		t.Fatalf("SaveWorkspace: %v", err)
+	}
+	d := NewContext()
+	if err := d.LoadWorkspace(bytes.NewReader(bb.Bytes())); err != nil {
+		t.Fatalf("LoadWorkspace: %v", err)
+	}
+	if got, want := d.Globals["A"].String(), c.Globals["A"].String(); got != want {
+		t.Errorf("Got %q want %q", got, want)
+	}
+}
+
+// Huge exact results are refused quickly, instead of computed for minutes.
+func TestExactTooLarge(t *testing.T) {
+	c := NewContext()
+	for _, src := range []string{
+		`(exact 3) ** 30000000`,
+		`1r3 ** 30000000`,
+		`(2x ** 600000) * 2x ** 600000`,
+		`*/ 10 rho 2x ** 200000`,
+	} {
+		start := time.Now()
+		_, err := c.EvalString(src)
+		if e, ok := err.(*LivyError); !ok || e.Kind != WsFullError {
+			t.Errorf("%s: got %v, wanted a WS FULL ERROR", src, err)
+		}
+		if d := time.Since(start); d > 2*time.Second {
+			t.Errorf("%s: took %v", src, d)
+		}
+	}
+	for src, want := range map[string]string{
+		`1x ** 30000000`:    "1x ",
+		`-1x ** 30000001`:   "-1x ",
+		`(2x ** 64) * 3x`:   "55340232221128654848x ",
+		`(1r2 ** 64) * 1r3`: "1r55340232221128654848 ",
+	} {
+		if got := evalIn(c, src).String(); got != want {
+			t.Errorf("%s: got %s, wanted %s", src, got, want)
+		}
+	}
+	if _, err := c.EvalString(`2x ** 100000`); err != nil {
+		t.Errorf("2x ** 100000: %v", err)
+	}
+}
+
+// Exact division by zero is a DOMAIN ERROR, not an inexact infinity.
+func TestExactDivideByZero(t *testing.T) {
+	c := NewContext()
+	for _, src := range []string{
+		`1x div 0x`,
+		`1r3 / 0x`,
+		`2x / 0`,
+		`0x ** -1`,
+		`0r1 ** -3x`,
+	} {
+		_, err := c.EvalString(src)
+		if e, ok := err.(*LivyError); !ok || e.Kind != DomainError {
+			t.Errorf("%s: got %v, wanted a DOMAIN ERROR", src, err)
+		}
+	}
+	// Inexact operands keep their float results.
+	if got := evalIn(c, `0 ** -1`).String(); got != "+Inf " {
+		t.Errorf("0 ** -1: got %q, wanted +Inf", got)
+	}
+}
@@ -0,0 +1,202 @@
+package livy
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MaxPrintPrecision is the most significant digits that $PP may ask for.
+const MaxPrintPrecision = 17
+
+// FormatNum formats a number with the context's FormatReal, FormatImagPlus,
+// FormatImagMinus, FormatComplexPlus, and FormatComplexMinus settings.
+// The imaginary magnitude is always passed as nonnegative;
+// the Plus or Minus format supplies the sign.
+// If PrintPrecision is set, the `%g` verbs show that many significant digits.
+func (c *Context) FormatNum(x complex128) string {
+	if c.PrintPrecision > 0 {
+		return c.formatNumVerb(x, fmt.Sprintf("%%.%dg", c.PrintPrecision))
+	}
+	return c.formatNumVerb(x, "%g")
+}
+
+// FormatNumDecimals formats a number like FormatNum,
+// but with the `%g` verbs of the settings replaced to show d decimal places,
+// or if d is negative, exponential notation with -d decimal places.
+func (c *Context) FormatNumDecimals(x complex128, d int) string {
+	verb := fmt.Sprintf("%%.%df", d)
+	if d < 0 {
+		verb = fmt.Sprintf("%%.%de", -d)
+	}
+	return c.formatNumVerb(x, verb)
+}
+
+// formatNumVerb formats a number with the `%g` verbs of the settings replaced by verb.
+func (c *Context) formatNumVerb(x complex128, verb string) string {
+	f := func(format, dflt string) string {
+		return strings.ReplaceAll(formatOr(format, dflt), "%g", verb)
+	}
+	rl, im := real(x), imag(x)
+	switch {
+	case im == 0:
+		return fmt.Sprintf(f(c.FormatReal, "%g"), rl)
+	case rl == 0 && im > 0:
+		return fmt.Sprintf(f(c.FormatImagPlus, "+j%g"), im)
+	case rl == 0 && im < 0:
+		return fmt.Sprintf(f(c.FormatImagMinus, "-j%g"), -im)
+	case im > 0:
+		return fmt.Sprintf(f(c.FormatComplexPlus, "%g+j%g"), rl, im)
+	default:
+		return fmt.Sprintf(f(c.FormatComplexMinus, "%g-j%g"), rl, -im)
+	}
+}
+
+func formatOr(format, dflt string) string {
+	if format == "" {
+		return dflt
+	}
+	return format
+}
+
+// prettyCell renders an element like Val.Pretty, but formats numbers with FormatNum.
+func (c *Context) prettyCell(v Val) string {
+	if num, ok := v.GetScalarOrNil().(Num); ok {
+		return c.FormatNum(num.F) + "  "
+	}
+	return v.Pretty()
+}
+
+// Pretty renders a value for printing, like Val.Pretty,
+// but with numbers formatted by FormatNum, so the print precision applies.
+func (c *Context) Pretty(v Val) string {
+	if t, ok := v.(*Mat); ok {
+		if len(t.S) == 0 || t.Len() == 0 {
+			return v.Pretty()
+		}
+		return renderMatrix(*t, c.prettyCell)
+	}
+	return c.prettyCell(v)
+}
+
+// monadicFormat renders the array as text, with the layout of RenderPrettyMatrix,
+// and returns it as a char vector (for a scalar or vector) or a char matrix.
+func monadicFormat(c *Context, b Val, axis int) Val {
+	var text string
+	switch t := b.(type) {
+	case *Mat:
+		if len(t.S) == 0 || t.Len() == 0 {
+			return StringMat("")
+		}
+		text = renderMatrix(*t, c.prettyCell)
+	default:
+		text = c.prettyCell(b)
+	}
+
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " ")
+	}
+	if len(lines) == 1 {
+		if m, ok := b.(*Mat); !ok || len(m.S) < 2 {
+			return StringMat(lines[0])
+		}
+	}
+	return charMatrix(lines)
+}
+
+// charMatrix makes a char matrix with a row for each line, padded with spaces.
+func charMatrix(lines []string) *Mat {
+	width := 0
+	for _, line := range lines {
+		if n := len([]rune(line)); n > width {
+			width = n
+		}
+	}
+	vec := make([]Val, 0, len(lines)*width)
+	for _, line := range lines {
+		n := 0
+		for _, r := range line {
+			vec = append(vec, &Char{r})
+			n++
+		}
+		for ; n < width; n++ {
+			vec = append(vec, &Char{' '})
+		}
+	}
+	return &Mat{M: vec, S: []int{len(lines), width}}
+}
+
+// dyadicFormat formats numbers with a fixed width and number of decimals:
+// `W D format X` uses the pair for every column of X,
+// or the LHS may give a pair for each column.
+// A width of 0 means just wide enough, plus a space.
+// A number too wide for its column shows as stars.
+func dyadicFormat(c *Context, a, b Val, axis int) Val {
+	spec := c.ScalarInts(a)
+
+	var shape []int
+	var elems []Val
+	switch t := b.(type) {
+	case *Mat:
+		shape, elems = t.S, t.Vals()
+	default:
+		elems = []Val{b}
+	}
+	cols := 1
+	if len(shape) > 0 {
+		cols = shape[len(shape)-1]
+	}
+
+	switch {
+	case len(spec) == 2:
+		for len(spec) < 2*cols {
+			spec = append(spec, spec[0], spec[1])
+		}
+	case len(spec) == 2*cols:
+	default:
+		Panicf(LengthError, "format wants 2 numbers (width and decimals) or 2 per column on the left, but got %d for %d columns", len(spec), cols)
+	}
+
+	// Format every element, before widths of 0 are known.
+	ss := make([]string, len(elems))
+	for i, e := range elems {
+		num, ok := e.GetScalarOrNil().(Num)
+		if !ok {
+			Panicf(DomainError, "format with width and decimals wants numbers, but got %s", e)
+		}
+		ss[i] = c.FormatNumDecimals(num.F, spec[2*(i%cols)+1])
+	}
+	widths := make([]int, cols)
+	for j := 0; j < cols; j++ {
+		widths[j] = spec[2*j]
+		if widths[j] < 0 {
+			Panicf(DomainError, "format width cannot be negative: %d", widths[j])
+		}
+		if widths[j] == 0 {
+			for i := j; i < len(ss); i += cols {
+				if len(ss[i])+1 > widths[j] {
+					widths[j] = len(ss[i]) + 1
+				}
+			}
+		}
+	}
+
+	var bb strings.Builder
+	for i, s := range ss {
+		w := widths[i%cols]
+		if len(s) > w {
+			s = strings.Repeat("*", w)
+		}
+		fmt.Fprintf(&bb, "%*s", w, s)
+	}
+
+	z := StringMat(bb.String())
+	rowWidth := 0
+	for _, w := range widths {
+		rowWidth += w
+	}
+	if len(shape) > 0 {
+		z.S = append(append([]int{}, shape[:len(shape)-1]...), rowWidth)
+	}
+	return z
+}
@@ -0,0 +1,138 @@
+package livy
+
+// Frame holds the local variables and function operands
+// of one call of a user-defined function or lambda.
+//
+// Variables are scoped lexically:  a name is found in the current frame,
+// then in the frames lexically enclosing it (for lambdas written inside a def),
+// and finally in the globals.  A def's frame has no parent,
+// so a called function cannot see or clobber its caller's locals.
+type Frame struct {
+	Name   string // Name of the function, for messages.
+	Vars   map[string]Val
+	Funcs  map[string]*Function // Function operands of a user-defined operator.
+	Parent *Frame               // Lexically enclosing frame, or nil.
+
+	// Source text of the function, and its offset in the text it was parsed from,
+	// for reporting the position of errors.
+	Source string
+	Start  int
+}
+
+// CurrentFrame is the frame of the innermost call in progress,
+// or nil at top level.
+func (c *Context) CurrentFrame() *Frame {
+	if len(c.Frames) == 0 {
+		return nil
+	}
+	return c.Frames[len(c.Frames)-1]
+}
+
+// GetVar finds the named variable in the current frame,
+// its lexical parents, and then the globals.
+// A system variable, named with `$`, is found in the Context.
+func (c *Context) GetVar(name string) (Val, bool) {
+	if IsSystemName(name) {
+		return c.getSystemVar(name), true
+	}
+	for f := c.CurrentFrame(); f != nil; f = f.Parent {
+		if z, ok := f.Vars[name]; ok {
+			return z, true
+		}
+	}
+	z, ok := c.Globals[name]
+	return z, ok
+}
+
+// SetVar assigns to the named variable in the innermost frame that has it.
+// Inside a function, assigning an undeclared name creates it in the current frame,
+// so it stays local.  At top level, it assigns a global.
+// Assigning a system variable changes a setting of the Context.
+func (c *Context) SetVar(name string, val Val) {
+	if IsSystemName(name) {
+		c.setSystemVar(name, val)
+		return
+	}
+	top := c.CurrentFrame()
+	if top == nil {
+		c.Globals[name] = val
+		return
+	}
+	for f := top; f != nil; f = f.Parent {
+		if _, ok := f.Vars[name]; ok {
+			f.Vars[name] = val
+			return
+		}
+	}
+	top.Vars[name] = val
+}
+
+// LookupFunc finds a function operand bound in the current frame or its lexical parents.
+func (c *Context) LookupFunc(name string) (*Function, bool) {
+	for f := c.CurrentFrame(); f != nil; f = f.Parent {
+		if fn, ok := f.Funcs[name]; ok {
+			return fn, true
+		}
+	}
+	return nil, false
+}
+
+// MonadicNamed finds the monadic meaning of a name,
+// preferring function operands in scope over the operator table.
+func (c *Context) MonadicNamed(name string) (MonadicFunc, bool) {
+	if fn, ok := c.LookupFunc(name); ok {
+		return fn.Monadic, fn.Monadic != nil
+	}
+	fn, ok := c.Monadics[name]
+	return fn, ok
+}
+
+// DyadicNamed finds the dyadic meaning of a name,
+// preferring function operands in scope over the operator table.
+func (c *Context) DyadicNamed(name string) (DyadicFunc, bool) {
+	if fn, ok := c.LookupFunc(name); ok {
+		return fn.Dyadic, fn.Dyadic != nil
+	}
+	fn, ok := c.Dyadics[name]
+	return fn, ok
+}
+
+// DefaultMaxDepth is the usual limit on nested calls of user functions,
+// well short of where the Go stack would overflow.
+const DefaultMaxDepth = 10000
+
+// callUser evaluates the body of a user-defined function in a new frame,
+// with the locals starting at 0, then the args bound.
+// An error escaping the call learns the function's name and source.
+func callUser(c *Context, frame *Frame, locals []string, args map[string]Val, seq *Seq) Val {
+	c.Step()
+	if c.MaxDepth > 0 && len(c.Frames) >= c.MaxDepth {
+		Panicf(WsFullError, "more than %d nested calls of user functions", c.MaxDepth)
+	}
+	frame.Vars = make(map[string]Val)
+	for _, lvar := range locals {
+		frame.Vars[lvar] = &Num{0}
+	}
+	for k, v := range args {
+		frame.Vars[k] = v
+	}
+
+	c.Frames = append(c.Frames, frame)
+	defer func() {
+		c.Frames = c.Frames[:len(c.Frames)-1]
+		r := recover()
+		if r == nil {
+			return
+		}
+		if e := AsLivyError(r); e != nil {
+			if e.Source == "" && e.Pos >= 0 {
+				e.Source = frame.Source
+				e.Pos -= frame.Start
+			}
+			e.Calls = append(e.Calls, frame.Name)
+			panic(e)
+		}
+		panic(r)
+	}()
+	return seq.Eval(c)
+}
@@ -0,0 +1,338 @@
+package livy
+
+import (
+	"fmt"
+)
+
+// Function is the value of anything standing in operator position:
+// its monadic and dyadic meanings.  Either may be nil.
+type Function struct {
+	Name    string
+	Monadic MonadicFunc
+	Dyadic  DyadicFunc
+}
+
+// FuncExpression is parsed from operator position,
+// and evaluates to a Function.
+type FuncExpression interface {
+	EvalFunc(*Context) *Function
+	String() string
+}
+
+// OpRef names an operator by its token,
+// which may also be one of the lexer's reduce, scan, each, or product forms.
+type OpRef struct {
+	Token *Token
+}
+
+// Lambda is an anonymous function `{ ... }` using X for the LHS and Y for the RHS.
+type Lambda struct {
+	Seq    *Seq
+	Source string
+	Start  int // Offset of Source in the text it was parsed from.
+}
+
+// Derived applies a builtin operator like reduce or inner product
+// to function operands that the lexer could not combine, such as lambdas.
+type Derived struct {
+	Kind  string // one of "/", "\\", "~", ".", ".."
+	Left  FuncExpression
+	Right FuncExpression // Only for inner product.
+}
+
+// OperatorCall applies a user-defined operator to its operands,
+// like `double power 3`, deriving a function.
+// The right operand is either a function or a value.
+type OperatorCall struct {
+	Op       string
+	Left     FuncExpression
+	Right    FuncExpression
+	RightVal Expression
+}
+
+// UserOperator holds the defs of an operator defined like `def (f power N) Y`,
+// which derives a monadic function, or `def X (f power N) Y`, which derives a dyadic one.
+type UserOperator struct {
+	Name    string
+	Arity   int // Number of operands: 1 or 2.
+	Monadic *Def
+	Dyadic  *Def
+}
+
+// FuncAssign gives a name to a function, like `f = { X + Y }`.
+type FuncAssign struct {
+	Name   string
+	Fn     FuncExpression
+	Source string
+}
+
+func (o *Function) GetMonadic() MonadicFunc {
+	if o.Monadic == nil {
+		Panicf(ValueError, "No such monadic operator %q", o.Name)
+	}
+	return o.Monadic
+}
+
+func (o *Function) GetDyadic() DyadicFunc {
+	if o.Dyadic == nil {
+		Panicf(ValueError, "No such dyadic operator %q", o.Name)
+	}
+	return o.Dyadic
+}
+
+func (o OpRef) EvalFunc(c *Context) *Function {
+	t := o.Token
+	z := &Function{Name: t.Str}
+	switch t.Type {
+	case OperatorToken:
+		z.Monadic, _ = c.MonadicNamed(t.Str)
+		z.Dyadic, _ = c.DyadicNamed(t.Str)
+	case ReduceToken, ScanToken:
+		op1 := t.Match[1]
+		fn1, ok := c.DyadicNamed(op1)
+		if !ok {
+			Panicf(ValueError, "Reduce or Scan syntax: No such dyadic operator %q", op1)
+		}
+		identity, ok := IdentityValueOfDyadic[op1]
+		if !ok {
+			identity = Zero
+		}
+		z.Monadic = MkReduceOrScanOp(t.Str, fn1, identity, t.Type == ScanToken)
+		if kernel, ok := c.CxKernel(op1); ok {
+			z.Monadic = withPackedReduceOrScan(z.Monadic, kernel, t.Type == ScanToken)
+		}
+	case EachToken:
+		op1 := t.Match[1]
+		if fn1, ok := c.MonadicNamed(op1); ok {
+			z.Monadic = MkEachOpMonadic(t.Str, fn1)
+		}
+		if fn1, ok := c.DyadicNamed(op1); ok {
+			z.Dyadic = MkEachOpDyadic(t.Str, fn1)
+		}
+		if z.Monadic == nil && z.Dyadic == nil {
+			Panicf(ValueError, "Each syntax: No such operator %q", op1)
+		}
+	case InnerProductToken:
+		op1 := t.Match[1]
+		fn1, ok := c.DyadicNamed(op1)
+		if !ok {
+			Panicf(ValueError, "Inner product syntax: No such dyadic operator %q", op1)
+		}
+		op2 := t.Match[2]
+		fn2, ok := c.DyadicNamed(op2)
+		if !ok {
+			Panicf(ValueError, "Inner product syntax: No such dyadic operator %q", op2)
+		}
+		z.Dyadic = mkInnerProduct(t.Str, fn1, fn2, c.parallelDyadic(op1) && c.parallelDyadic(op2))
+		kernel1, ok1 := c.CxKernel(op1)
+		kernel2, ok2 := c.CxKernel(op2)
+		if ok1 && ok2 {
+			z.Dyadic = withPackedInnerProduct(z.Dyadic, kernel1, kernel2)
+		}
+	case OuterProductToken:
+		op1 := t.Match[1]
+		fn1, ok := c.DyadicNamed(op1)
+		if !ok {
+			Panicf(ValueError, "Outer product syntax: No such dyadic operator %q", op1)
+		}
+		z.Dyadic = mkOuterProduct(t.Str, fn1, c.parallelDyadic(op1))
+	default:
+		Log.Panicf("Default case: token %v", t.Type)
+	}
+	return z
+}
+
+// EvalFunc captures the current frame, so the lambda can see
+// the locals of the function it is written in.
+func (o Lambda) EvalFunc(c *Context) *Function {
+	parent := c.CurrentFrame()
+	return &Function{
+		Name: o.Source,
+		Monadic: func(c *Context, b Val, axis int) Val {
+			frame := &Frame{Name: o.Source, Parent: parent, Source: o.Source, Start: o.Start}
+			return callUser(c, frame, []string{"Y"}, map[string]Val{"Y": b}, o.Seq)
+		},
+		Dyadic: func(c *Context, a Val, b Val, axis int) Val {
+			frame := &Frame{Name: o.Source, Parent: parent, Source: o.Source, Start: o.Start}
+			return callUser(c, frame, []string{"X", "Y"}, map[string]Val{"X": a, "Y": b}, o.Seq)
+		},
+	}
+}
+
+func (o Derived) EvalFunc(c *Context) *Function {
+	left := o.Left.EvalFunc(c)
+	z := &Function{Name: o.String()}
+	switch o.Kind {
+	case "/", `\`:
+		z.Monadic = MkReduceOrScanOp(z.Name, left.GetDyadic(), identityOf(o.Left), o.Kind == `\`)
+	case "~":
+		if left.Monadic != nil {
+			z.Monadic = MkEachOpMonadic(z.Name, left.Monadic)
+		}
+		if left.Dyadic != nil {
+			z.Dyadic = MkEachOpDyadic(z.Name, left.Dyadic)
+		}
+	case ".":
+		right := o.Right.EvalFunc(c)
+		z.Dyadic = MkInnerProduct(z.Name, left.GetDyadic(), right.GetDyadic())
+	case "..":
+		z.Dyadic = MkOuterProduct(z.Name, left.GetDyadic())
+	default:
+		Log.Panicf("Unknown derived function kind %q", o.Kind)
+	}
+	return z
+}
+
+// identityOf returns the identity value of a builtin dyadic operator,
+// or nil if none is known, in which case reducing an empty axis is a DOMAIN ERROR.
+func identityOf(fn FuncExpression) Val {
+	if r, ok := fn.(*OpRef); ok && r.Token.Type == OperatorToken {
+		return IdentityValueOfDyadic[r.Token.Str]
+	}
+	return nil
+}
+
+func (o OperatorCall) EvalFunc(c *Context) *Function {
+	op, ok := c.Operators[o.Op]
+	if !ok {
+		Panicf(ValueError, "No such user-defined operator %q", o.Op)
+	}
+	left := o.Left.EvalFunc(c)
+	var right *Function
+	var rightVal Val
+	if o.Right != nil {
+		right = o.Right.EvalFunc(c)
+	}
+	if o.RightVal != nil {
+		rightVal = EvalFor(c, o.RightVal, "right operand of operator", o.Op)
+	}
+
+	bind := func(def *Def) (map[string]*Function, map[string]Val) {
+		funcs := map[string]*Function{def.LeftOperand: left}
+		operands := make(map[string]Val)
+		switch {
+		case def.RightOperand == "":
+		case isFuncName(def.RightOperand):
+			if right == nil {
+				Panicf(DomainError, "operator %q wants a function as right operand, but got a value", o.Op)
+			}
+			funcs[def.RightOperand] = right
+		default:
+			if rightVal == nil {
+				Panicf(DomainError, "operator %q wants a value as right operand, but got a function", o.Op)
+			}
+			operands[def.RightOperand] = rightVal
+		}
+		return funcs, operands
+	}
+
+	z := &Function{Name: o.String()}
+	if op.Monadic != nil {
+		def := op.Monadic
+		z.Monadic = func(c *Context, b Val, axis int) Val {
+			funcs, operands := bind(def)
+			return def.Call(c, nil, b, axis, funcs, operands)
+		}
+	}
+	if op.Dyadic != nil {
+		def := op.Dyadic
+		z.Dyadic = func(c *Context, a Val, b Val, axis int) Val {
+			funcs, operands := bind(def)
+			return def.Call(c, a, b, axis, funcs, operands)
+		}
+	}
+	return z
+}
+
+// isFuncName tells if the name is spelled like a function, not a variable.
+func isFuncName(name string) bool {
+	return MatchVariable(name) == nil
+}
+
+// DefineOperator installs a def with operands as a user-defined operator.
+func (c *Context) DefineOperator(def *Def) {
+	arity := 1
+	if def.RightOperand != "" {
+		arity = 2
+	}
+	if c.Operators == nil {
+		c.Operators = make(map[string]*UserOperator)
+	}
+	op, ok := c.Operators[def.Name]
+	if !ok || op.Arity != arity {
+		op = &UserOperator{Name: def.Name, Arity: arity}
+		c.Operators[def.Name] = op
+	}
+	if def.Lhs == "" {
+		op.Monadic = def
+	} else {
+		op.Dyadic = def
+	}
+}
+
+func (o FuncAssign) Eval(c *Context) Val {
+	fn := o.Fn.EvalFunc(c)
+	// A lambda assigned in a call sees its locals, which its source cannot save.
+	captured := c.CurrentFrame() != nil && hasLambda(o.Fn)
+	if fn.Monadic != nil {
+		c.Monadics[o.Name] = fn.Monadic
+		c.RememberDef(false, o.Name, o.Source)
+		if captured {
+			c.markCaptured(false, o.Name)
+		}
+	}
+	if fn.Dyadic != nil {
+		c.Dyadics[o.Name] = fn.Dyadic
+		c.RememberDef(true, o.Name, o.Source)
+		if captured {
+			c.markCaptured(true, o.Name)
+		}
+	}
+	return &Box{"def"}
+}
+
+// hasLambda tells if a function expression contains a lambda,
+// which captures the frame it is evaluated in.
+func hasLambda(fn FuncExpression) bool {
+	switch x := fn.(type) {
+	case *Lambda:
+		return true
+	case *Derived:
+		return hasLambda(x.Left) || (x.Right != nil && hasLambda(x.Right))
+	case *OperatorCall:
+		return hasLambda(x.Left) || (x.Right != nil && hasLambda(x.Right))
+	}
+	return false
+}
+
+func (o OpRef) String() string {
+	return o.Token.Str
+}
+
+func (o Lambda) String() string {
+	return o.Source
+}
+
+func (o Derived) String() string {
+	switch o.Kind {
+	case ".":
+		return fmt.Sprintf("%s.%s", o.Left, o.Right)
+	case "..":
+		return fmt.Sprintf("..%s", o.Left)
+	}
+	return fmt.Sprintf("%s%s", o.Left, o.Kind)
+}
+
+func (o OperatorCall) String() string {
+	switch {
+	case o.Right != nil:
+		return fmt.Sprintf("%s %s %s", o.Left, o.Op, o.Right)
+	case o.RightVal != nil:
+		return fmt.Sprintf("%s %s %s", o.Left, o.Op, o.RightVal)
+	}
+	return fmt.Sprintf("%s %s", o.Left, o.Op)
+}
+
+func (o FuncAssign) String() string {
+	return fmt.Sprintf("FuncAssign(%s = %s)", o.Name, o.Fn)
+}
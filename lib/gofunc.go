@@ -0,0 +1,176 @@
+package livy
+
+import (
+	"fmt"
+	"reflect"
+)
+
+var valType = reflect.TypeOf((*Val)(nil)).Elem()
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// RegisterGoFunc makes a Go function a builtin,
+// monadic if it takes one argument, or dyadic if it takes two.
+//
+// Arguments may be float64, complex128, int, string, []float64, [][]float64,
+// []complex128, []string, or Val.  If they are all numbers (float64, complex128,
+// or int), fn is applied to each element, like the scalar functions are,
+// in order and in one goroutine.  Otherwise fn gets the whole arguments.
+//
+// The result may be anything that ToVal converts, optionally followed by an error,
+// which is raised as a DOMAIN ERROR (or as itself, if it is a *LivyError).
+func (r *Registry) RegisterGoFunc(name string, fn interface{}) error {
+	f := reflect.ValueOf(fn)
+	if fn == nil || f.Kind() != reflect.Func || f.IsNil() {
+		return fmt.Errorf("RegisterGoFunc %q: not a func: %T", name, fn)
+	}
+	t := f.Type()
+	if t.IsVariadic() || t.NumIn() < 1 || t.NumIn() > 2 {
+		return fmt.Errorf("RegisterGoFunc %q: must take 1 or 2 arguments: %v", name, t)
+	}
+	if t.NumOut() < 1 || t.NumOut() > 2 || (t.NumOut() == 2 && t.Out(1) != errorType) {
+		return fmt.Errorf("RegisterGoFunc %q: must return a value, or a value and an error: %v", name, t)
+	}
+	if !goResultOK(t.Out(0)) {
+		return fmt.Errorf("RegisterGoFunc %q: cannot convert result type %v", name, t.Out(0))
+	}
+
+	scalar := true
+	args := make([]goArgFunc, t.NumIn())
+	for i := range args {
+		args[i] = goArg(name, t.In(i))
+		if args[i] == nil {
+			return fmt.Errorf("RegisterGoFunc %q: cannot convert argument type %v", name, t.In(i))
+		}
+		switch t.In(i).Kind() {
+		case reflect.Float64, reflect.Complex128, reflect.Int:
+		default:
+			scalar = false
+		}
+	}
+
+	call := func(in ...reflect.Value) Val {
+		out := f.Call(in)
+		if len(out) == 2 && !out[1].IsNil() {
+			err := out[1].Interface().(error)
+			if e, ok := err.(*LivyError); ok {
+				panic(e)
+			}
+			Panicf(DomainError, "%s: %v", name, err)
+		}
+		z, err := ToVal(goResult(out[0]))
+		if err != nil {
+			Panicf(DomainError, "%s: %v", name, err)
+		}
+		return z
+	}
+
+	if t.NumIn() == 1 {
+		var mf MonadicFunc = func(c *Context, b Val, axis int) Val {
+			return call(args[0](c, b))
+		}
+		if scalar {
+			mf = WrapMatMonadic(mf)
+		}
+		r.RegisterMonadic(name, mf)
+	} else {
+		var df DyadicFunc = func(c *Context, a, b Val, axis int) Val {
+			return call(args[0](c, a), args[1](c, b))
+		}
+		if scalar {
+			df = WrapMatMatDyadic(df)
+		}
+		r.RegisterDyadic(name, df)
+	}
+	return nil
+}
+
+// goArgFunc converts a livy value to a Go argument.
+type goArgFunc func(c *Context, v Val) reflect.Value
+
+// goArg finds the conversion to Go arguments of the type, or nil.
+// Failures to convert are raised as DOMAIN ERRORs, naming the function.
+func goArg(name string, t reflect.Type) goArgFunc {
+	switch t {
+	case valType:
+		return func(c *Context, v Val) reflect.Value {
+			return reflect.ValueOf(&v).Elem()
+		}
+	case reflect.TypeOf([]float64(nil)):
+		return goSliceArg(name, func(v Val) (interface{}, error) { return GetFloats(v) })
+	case reflect.TypeOf([][]float64(nil)):
+		return goSliceArg(name, func(v Val) (interface{}, error) { return GetFloatMatrix(v) })
+	case reflect.TypeOf([]complex128(nil)):
+		return goSliceArg(name, func(v Val) (interface{}, error) { return GetCxs(v) })
+	case reflect.TypeOf([]string(nil)):
+		return goSliceArg(name, func(v Val) (interface{}, error) { return GetStrings(v) })
+	}
+
+	switch t.Kind() {
+	case reflect.Float64:
+		return func(c *Context, v Val) reflect.Value {
+			x := v.GetScalarCx()
+			if imag(x) != 0 {
+				Panicf(DomainError, "%s: not a real number: %s", name, Cx2Str(x))
+			}
+			return reflect.ValueOf(real(x)).Convert(t)
+		}
+	case reflect.Complex128:
+		return func(c *Context, v Val) reflect.Value {
+			return reflect.ValueOf(v.GetScalarCx()).Convert(t)
+		}
+	case reflect.Int:
+		return func(c *Context, v Val) reflect.Value {
+			return reflect.ValueOf(c.ScalarInt(v)).Convert(t)
+		}
+	case reflect.String:
+		return func(c *Context, v Val) reflect.Value {
+			s, ok := GetString(v)
+			if !ok {
+				Panicf(DomainError, "%s: not a string: %s", name, v)
+			}
+			return reflect.ValueOf(s).Convert(t)
+		}
+	}
+	return nil
+}
+
+func goSliceArg(name string, get func(v Val) (interface{}, error)) goArgFunc {
+	return func(c *Context, v Val) reflect.Value {
+		x, err := get(v)
+		if err != nil {
+			Panicf(DomainError, "%s: %v", name, err)
+		}
+		return reflect.ValueOf(x)
+	}
+}
+
+// goResultOK tells if results of the type can be converted by goResult and ToVal.
+func goResultOK(t reflect.Type) bool {
+	switch t {
+	case valType, reflect.TypeOf([]float64(nil)), reflect.TypeOf([][]float64(nil)),
+		reflect.TypeOf([]complex128(nil)), reflect.TypeOf([]string(nil)), reflect.TypeOf([]int(nil)):
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Float64, reflect.Complex128, reflect.Int, reflect.Bool, reflect.String:
+		return true
+	}
+	return false
+}
+
+// goResult makes a Go result the plain type that ToVal takes.
+func goResult(x reflect.Value) interface{} {
+	switch x.Kind() {
+	case reflect.Float64:
+		return x.Float()
+	case reflect.Complex128:
+		return x.Complex()
+	case reflect.Int:
+		return int(x.Int())
+	case reflect.Bool:
+		return x.Bool()
+	case reflect.String:
+		return x.String()
+	}
+	return x.Interface()
+}
@@ -0,0 +1,122 @@
+package livy
+
+import (
+	"errors"
+	"math"
+	"math/cmplx"
+	"strings"
+	"testing"
+)
+
+func TestRegisterGoFunc(t *testing.T) {
+	r := NewRegistry()
+	for name, fn := range map[string]interface{}{
+		"cube":     func(x float64) float64 { return x * x * x },
+		"conjg":    cmplx.Conj,
+		"hyp":      math.Hypot,
+		"repeat":   func(s string, n int) string { return strings.Repeat(s, n) },
+		"words":    strings.Fields,
+		"total":    func(xs []float64) float64 { return xs[0] + xs[len(xs)-1] },
+		"rowsums":  rowSums,
+		"isqrt":    checkedSqrt,
+		"even":     func(i int) bool { return i%2 == 0 },
+		"shapeof":  func(v Val) []int { return v.Shape() },
+		"joinwith": func(sep string, ss []string) string { return strings.Join(ss, sep) },
+		"ragged":   func(n int) [][]float64 { return [][]float64{{1}, make([]float64, n)} },
+	} {
+		if err := r.RegisterGoFunc(name, fn); err != nil {
+			t.Fatalf("RegisterGoFunc(%q): %v", name, err)
+		}
+	}
+	c := r.NewContext()
+	for _, tc := range []struct{ src, want string }{
+		{`cube 1 2 3`, `[3 ]{1 8 27 } `},
+		{`cube 2 2 rho 2`, `[2 2 ]{8 8 8 8 } `},
+		{`conjg 1+j2`, `1-j2 `},
+		{`3 hyp 4`, `5 `},
+		{`3 5 hyp 4 12`, `[2 ]{5 13 } `},
+		{`3 5 hyp 4`, `[2 ]{5 6.4031242374328485 } `},
+		{`"ab" repeat 3`, `[6 ]{'a' 'b' 'a' 'b' 'a' 'b' } `},
+		{`rho words "to be or not"`, `[1 ]{4 } `},
+		{`total 10 20 30`, `40 `},
+		{`rowsums 2 3 rho iota 6`, `[2 ]{3 12 } `},
+		{`isqrt 16 25`, `[2 ]{4 5 } `},
+		{`even iota 4`, `[4 ]{1 0 1 0 } `},
+		{`shapeof 2 3 rho 0`, `[2 ]{2 3 } `},
+		{`"," joinwith words "a b c"`, `[5 ]{'a' ',' 'b' ',' 'c' } `},
+	} {
+		z, err := c.EvalString(tc.src)
+		if err != nil {
+			t.Errorf("%s: %v", tc.src, err)
+		} else if got := z.String(); got != tc.want {
+			t.Errorf("%s: got %s, wanted %s", tc.src, got, tc.want)
+		}
+	}
+
+	for _, tc := range []struct {
+		src  string
+		kind ErrorKind
+	}{
+		{`isqrt 4 -1`, DomainError},
+		{`cube "a"`, DomainError},
+		{`2.5 repeat 2`, DomainError},
+		{`"a" repeat 1.5`, DomainError},
+		{`"a" repeat 1e30`, DomainError},
+		{`rowsums 1 2 3`, DomainError},
+		{`total 2 2 rho 1`, DomainError},
+		{`"," joinwith 1 2`, DomainError},
+		{`ragged 2`, DomainError},
+		{`1 2 hyp 3 4 5`, LengthError},
+	} {
+		_, err := c.EvalString(tc.src)
+		if e, ok := err.(*LivyError); !ok || e.Kind != tc.kind {
+			t.Errorf("%s: got %v, wanted a %s ERROR", tc.src, err, tc.kind)
+		}
+	}
+	// Conversion errors name the function.
+	if _, err := c.EvalString(`rowsums 1 2 3`); err == nil || !strings.Contains(err.Error(), "rowsums: ") {
+		t.Errorf("Got %v, wanted an error naming rowsums", err)
+	}
+}
+
+func TestRegisterGoFuncRejects(t *testing.T) {
+	r := NewRegistry()
+	for _, fn := range []interface{}{
+		nil,
+		42,
+		(func(float64) float64)(nil),
+		func() float64 { return 0 },
+		func(a, b, c float64) float64 { return 0 },
+		func(xs ...float64) float64 { return 0 },
+		func(x float64) {},
+		func(x float64) (float64, float64) { return 0, 0 },
+		func(x float32) float64 { return 0 },
+		func(x float64) map[string]int { return nil },
+	} {
+		if err := r.RegisterGoFunc("bad", fn); err == nil {
+			t.Errorf("RegisterGoFunc should reject %T", fn)
+		}
+	}
+	if _, ok := r.Monadics["bad"]; ok {
+		t.Errorf("A rejected function was registered")
+	}
+}
+
+func rowSums(xss [][]float64) []float64 {
+	var z []float64
+	for _, xs := range xss {
+		sum := 0.0
+		for _, x := range xs {
+			sum += x
+		}
+		z = append(z, sum)
+	}
+	return z
+}
+
+func checkedSqrt(x float64) (float64, error) {
+	if x < 0 {
+		return 0, errors.New("negative")
+	}
+	return math.Sqrt(x), nil
+}
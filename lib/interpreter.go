@@ -0,0 +1,100 @@
+package livy
+
+import (
+	"context"
+	"time"
+)
+
+// Interpreter is the API for Go programs that embed livy.
+// Its methods never panic: failures are returned as errors,
+// which are *LivyError unless said otherwise.
+// An Interpreter must not be used by more than one goroutine at once.
+type Interpreter struct {
+	Context *Context
+
+	// PanicHook, if set, is called with what was recovered from a failed Eval,
+	// before it becomes an error.  The command line uses it for stack traces.
+	PanicHook func(r interface{})
+
+	// Timeout, if not 0, limits the wall-clock time of each Eval.
+	// For a limit on steps, set Context.MaxSteps.
+	Timeout time.Duration
+}
+
+// NewInterpreter makes an Interpreter with the standard functions,
+// and the default limits on array sizes.
+// For more builtins, use &Interpreter{Context: r.NewContext()} with a Registry.
+func NewInterpreter() *Interpreter {
+	c := NewContext()
+	c.UseDefaultLimits()
+	return &Interpreter{Context: c}
+}
+
+// Eval parses and evaluates the source text.
+func (in *Interpreter) Eval(src string) (val Val, err error) {
+	return in.EvalContext(context.Background(), src)
+}
+
+// EvalContext is like Eval, but stops with an INTERRUPT ERROR if ctx is done first.
+func (in *Interpreter) EvalContext(ctx context.Context, src string) (val Val, err error) {
+	if in.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, in.Timeout)
+		defer cancel()
+	}
+	c := in.Context
+	saved := c.GoContext
+	c.GoContext = ctx
+	defer func() { c.GoContext = saved }()
+
+	defer func() {
+		r := recover()
+		if r != nil {
+			if in.PanicHook != nil {
+				in.PanicHook(r)
+			}
+			val, err = nil, ErrorFromPanic(r, src)
+		}
+	}()
+	return in.Context.evalString(src), nil
+}
+
+// Interrupt stops the Eval in progress with an INTERRUPT ERROR.
+// It may be called from any goroutine.
+func (in *Interpreter) Interrupt() {
+	in.Context.Interrupt()
+}
+
+// Set assigns a global variable, converting the value with ToVal.
+// Assigning a system variable like $CT changes the setting, if the value is allowed.
+func (in *Interpreter) Set(name string, x interface{}) (err error) {
+	defer recoverError(&err)
+	if m := MatchVariable(name); m == nil || m[0] != name {
+		return &LivyError{Kind: SyntaxError, Msg: "not a variable name: " + name, Pos: -1}
+	}
+	v, err := ToVal(x)
+	if err != nil {
+		return err
+	}
+	in.Context.SetVar(name, v)
+	return nil
+}
+
+// Get returns a global or system variable, converted with FromVal.
+func (in *Interpreter) Get(name string) (x interface{}, err error) {
+	defer recoverError(&err)
+	v, ok := in.Context.GetVar(name)
+	if !ok {
+		return nil, &LivyError{Kind: ValueError, Msg: "no variable named " + name, Pos: -1}
+	}
+	return FromVal(v), nil
+}
+
+// recoverError is deferred, to return a panic as an error.
+func recoverError(err *error) {
+	if r := recover(); r != nil {
+		if e := ErrorFromPanic(r, ""); e != nil {
+			*err = e
+		}
+	}
+}
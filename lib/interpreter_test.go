@@ -0,0 +1,165 @@
+package livy
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+func TestInterpreterSetEvalGet(t *testing.T) {
+	in := NewInterpreter()
+	for name, x := range map[string]interface{}{
+		"F":  []float64{1.5, 2, 3},
+		"M":  [][]float64{{1, 2, 3}, {4, 5, 6}},
+		"C":  []complex128{1, 2i},
+		"S":  []string{"ab", "", "c"},
+		"T":  "hello",
+		"N":  2.5,
+		"X":  big.NewRat(1, 3),
+		"B":  true,
+		"I":  []int{4, 5},
+		"Z0": []float64{},
+	} {
+		if err := in.Set(name, x); err != nil {
+			t.Fatalf("Set(%q, %v): %v", name, x, err)
+		}
+		got, err := in.Get(name)
+		if err != nil {
+			t.Fatalf("Get(%q): %v", name, err)
+		}
+		want := x
+		switch x.(type) {
+		case bool:
+			want = 1.0
+		case []int:
+			want = []float64{4, 5}
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Get(%q) is %#v, wanted %#v", name, got, want)
+		}
+	}
+
+	if _, err := in.Eval(`R = +/ M ; Q = rho S ; P = 2 * C`); err != nil {
+		t.Fatalf("Eval: %v", err)
+	}
+	for name, want := range map[string]interface{}{
+		"R": []float64{6, 15},
+		"Q": []float64{3},
+		"P": []complex128{2, 4i},
+	} {
+		if got, err := in.Get(name); err != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("Get(%q) is %#v, %v; wanted %#v", name, got, err, want)
+		}
+	}
+	// Values that do not convert come back as themselves.
+	z, _ := in.Eval(`2 2 2 rho 1`)
+	if got, _ := in.Eval(`W = 2 2 2 rho 1 ; W`); !reflect.DeepEqual(FromVal(got), z) {
+		t.Errorf("Got %v, wanted the Mat itself", got)
+	}
+}
+
+func TestInterpreterErrors(t *testing.T) {
+	in := NewInterpreter()
+	for _, tc := range []struct {
+		err  error
+		kind ErrorKind
+	}{
+		{evalErr(in, `1 2 3 + 4 5`), LengthError},
+		{evalErr(in, `1 + `), SyntaxError},
+		{evalErr(in, `1 2 }`), SyntaxError},
+		{evalErr(in, `Nope`), ValueError},
+		{in.Set("lower", 1.0), SyntaxError},
+		{in.Set("A", struct{}{}), DomainError},
+		{in.Set("A", [][]float64{{1, 2}, {3}}), LengthError},
+		{in.Set("A", (*big.Int)(nil)), DomainError},
+		{getErr(in, "Missing"), ValueError},
+		{in.Set("$PP", 99), DomainError},
+		{in.Set("$Nope", 1), ValueError},
+		{in.Set("$TS", 1), SyntaxError},
+		{getErr(in, "$Nope"), ValueError},
+	} {
+		e, ok := tc.err.(*LivyError)
+		if !ok || e.Kind != tc.kind {
+			t.Errorf("Got %v, wanted a %s ERROR", tc.err, tc.kind)
+		}
+	}
+
+	hooked := false
+	in.PanicHook = func(r interface{}) { hooked = true }
+	evalErr(in, `iota -1`)
+	if !hooked {
+		t.Errorf("PanicHook was not called")
+	}
+}
+
+func TestGetConversionErrors(t *testing.T) {
+	c := NewContext()
+	if _, err := GetFloats(evalIn(c, `J * 1 2 3`)); err == nil {
+		t.Errorf("GetFloats should fail on complex numbers")
+	}
+	if _, err := GetFloats(evalIn(c, `2 2 rho 1`)); err == nil {
+		t.Errorf("GetFloats should fail on a matrix")
+	}
+	if _, err := GetFloatMatrix(evalIn(c, `1 2 3`)); err == nil {
+		t.Errorf("GetFloatMatrix should fail on a vector")
+	}
+	if _, err := GetStrings(evalIn(c, `1 2 3`)); err == nil {
+		t.Errorf("GetStrings should fail on numbers")
+	}
+	if got, err := GetFloats(evalIn(c, `1r2 , 3x`)); err != nil || !reflect.DeepEqual(got, []float64{0.5, 3}) {
+		t.Errorf("GetFloats of exact numbers is %v, %v", got, err)
+	}
+}
+
+func evalErr(in *Interpreter, src string) error {
+	_, err := in.Eval(src)
+	return err
+}
+
+func getErr(in *Interpreter, name string) error {
+	_, err := in.Get(name)
+	return err
+}
+
+func TestInterpreterSystemVars(t *testing.T) {
+	in := NewInterpreter()
+	if err := in.Set("$CT", 0.5); err != nil {
+		t.Fatalf("Set($CT): %v", err)
+	}
+	if in.Context.CompareTolerance != 0.5 {
+		t.Errorf("Set($CT) left the tolerance %g", in.Context.CompareTolerance)
+	}
+	if z, err := in.Eval(`$CT`); err != nil || z.String() != "0.5 " {
+		t.Errorf("Eval($CT) got %v, %v", z, err)
+	}
+	in.Eval(`$CT = 0.25`)
+	if got, err := in.Get("$CT"); err != nil || got != 0.25 {
+		t.Errorf("Get($CT) got %v, %v; wanted 0.25", got, err)
+	}
+	if _, ok := in.Context.Globals["$CT"]; ok {
+		t.Errorf("Set($CT) made a global")
+	}
+}
+
+// Integers too large for a float64 stay exact.
+func TestInterpreterSetBigInt(t *testing.T) {
+	in := NewInterpreter()
+	for name, x := range map[string]interface{}{
+		"A": int64(1<<53 + 1),
+		"B": int64(-1<<62 - 1),
+		"C": []int{0, 1<<53 + 1},
+	} {
+		if err := in.Set(name, x); err != nil {
+			t.Fatalf("Set(%q): %v", name, err)
+		}
+	}
+	for src, want := range map[string]string{
+		`A - 9007199254740992`:    "1x ",
+		`B + 4611686018427387904`: "-1x ",
+		`C[1] - 9007199254740992`: "[1 ]{1x } ",
+	} {
+		if z, err := in.Eval(src); err != nil || z.String() != want {
+			t.Errorf("%s: got %v, %v; wanted %q", src, z, err, want)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package livy
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// checkEvery is how many elements loops over large arrays do between checks for interrupts.
+const checkEvery = 1 << 16
+
+// Interrupt stops the evaluation in progress with an INTERRUPT ERROR, at its next check.
+// It may be called from any goroutine, such as one handling SIGINT.
+func (c *Context) Interrupt() {
+	atomic.StoreInt32(&c.interrupted, 1)
+}
+
+// startEval forgets old steps, interrupts, and elements made, before a top-level evaluation.
+func (c *Context) startEval() {
+	atomic.StoreInt64(&c.steps, 0)
+	atomic.StoreInt64(&c.elements, 0)
+	atomic.StoreInt32(&c.interrupted, 0)
+}
+
+// Check raises an INTERRUPT ERROR if evaluation must stop,
+// because of Interrupt or because GoContext is done.
+// It may be called from any goroutine.
+func (c *Context) Check() {
+	if atomic.LoadInt32(&c.interrupted) != 0 {
+		Panicf(InterruptError, "interrupted")
+	}
+	if c.GoContext != nil {
+		select {
+		case <-c.GoContext.Done():
+			if c.GoContext.Err() == context.DeadlineExceeded {
+				Panicf(InterruptError, "time limit exceeded")
+			}
+			Panicf(InterruptError, "canceled")
+		default:
+		}
+	}
+}
+
+// Step counts a step of evaluation, raising an INTERRUPT ERROR
+// if there are more than MaxSteps, then does Check.
+func (c *Context) Step() {
+	if n := atomic.AddInt64(&c.steps, 1); c.MaxSteps > 0 && n > c.MaxSteps {
+		Panicf(InterruptError, "more than %d steps", c.MaxSteps)
+	}
+	c.Check()
+}
+
+// checkedFor calls fn on consecutive ranges covering [lo, hi),
+// of checkEvery elements at most, doing Check before each.
+func (c *Context) checkedFor(lo, hi int, fn func(lo, hi int)) {
+	for lo < hi {
+		end := hi
+		if end-lo > checkEvery {
+			end = lo + checkEvery
+		}
+		c.Check()
+		fn(lo, end)
+		lo = end
+	}
+}
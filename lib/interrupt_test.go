@@ -0,0 +1,90 @@
+package livy
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func wantInterrupt(t *testing.T, err error, msg string) {
+	t.Helper()
+	e, ok := err.(*LivyError)
+	if !ok || e.Kind != InterruptError || !strings.Contains(e.Msg, msg) {
+		t.Errorf("Got %v, wanted an INTERRUPT ERROR about %q", err, msg)
+	}
+}
+
+func TestMaxSteps(t *testing.T) {
+	c := NewContext()
+	c.MaxSteps = 1000
+	for _, src := range []string{
+		`while 1 do 0 done`,
+		`def f Y { f Y } ; f 1`,
+		`{ Y + 1 }~ iota 5000`,
+		`try while 1 do 0 done catch E 5 end`,
+	} {
+		_, err := c.EvalString(src)
+		wantInterrupt(t, err, "more than 1000 steps")
+	}
+
+	// Each evaluation gets its own budget.
+	for i := 0; i < 3; i++ {
+		z, err := c.EvalString(`I = 0 ; while I < 600 do I = I + 1 done ; I`)
+		if err != nil || z.String() != "600 " {
+			t.Errorf("Got %v, %v; wanted 600", z, err)
+		}
+	}
+}
+
+func TestInterpreterTimeout(t *testing.T) {
+	in := NewInterpreter()
+	in.Timeout = 20 * time.Millisecond
+	_, err := in.Eval(`while 1 do 0 done`)
+	wantInterrupt(t, err, "time limit exceeded")
+
+	if z, err := in.Eval(`+/ iota 10`); err != nil || z.String() != "45 " {
+		t.Errorf("After a timeout, got %v, %v", z, err)
+	}
+}
+
+func TestInterpreterInterrupt(t *testing.T) {
+	in := NewInterpreter()
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		in.Interrupt()
+	}()
+	_, err := in.Eval(`def f Y { f Y } ; while 1 do 0 done`)
+	wantInterrupt(t, err, "interrupted")
+
+	// An interrupt between evaluations is forgotten.
+	in.Interrupt()
+	if _, err := in.Eval(`1 + 1`); err != nil {
+		t.Errorf("Got %v after an old interrupt", err)
+	}
+}
+
+func TestEvalContextCancelsArrays(t *testing.T) {
+	in := NewInterpreter()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, src := range []string{`iota 100000`, `100000 rho 1 2 3`, `(iota 1000) ..* iota 1000`} {
+		_, err := in.EvalContext(ctx, src)
+		wantInterrupt(t, err, "canceled")
+	}
+}
+
+// Only a Timeout is set, but runaway recursion must not overflow the Go stack.
+func TestRecursionWithTimeout(t *testing.T) {
+	in := NewInterpreter()
+	in.Timeout = 10 * time.Second
+	_, err := in.Eval(`def f X { f X } ; f 1`)
+	if e, ok := err.(*LivyError); !ok || e.Kind != WsFullError {
+		t.Errorf("Got %v, wanted a WS FULL ERROR", err)
+	}
+
+	z, err := in.Eval(`def g X { if X > 0 then 1 + g X - 1 else 0 fi } ; g 5000`)
+	if err != nil || z.String() != "5000 " {
+		t.Errorf("Got %v, %v; wanted 5000", z, err)
+	}
+}
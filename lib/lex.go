@@ -27,20 +27,25 @@ const (
 	ScanToken
 	EachToken
 	StringToken
+	TildeToken // 20
+	DotToken
 )
 
 const RE_JUST_OPERATOR = `([-+*/\\,&|!=<>]+|[a-z][A-Za-z0-9_]*)`
-const RE_OPERATOR = `([-+*/\\,&|!=<>]+|[a-z][A-Za-z0-9_]*[/\\]?)`
-const RE_KEYWORD = `(def|if|then|elif|else|fi|while|do|done|break|continue)\b`
+const RE_OPERATOR = `([-+*/\\,&|!=<>]+|[$]?[a-z][A-Za-z0-9_]*[/\\]?)`
+const RE_KEYWORD = `(def|if|then|elif|else|fi|while|do|done|break|continue|try|catch|end)\b`
 const RE_REAL = `([-+]?[0-9]+([.][0-9]+)?([eE][-+]?[0-9]+)?)`
 const RE_COMPLEX = RE_REAL + `?([+-][jJ])` + RE_REAL
+const RE_EXACT = `([-+]?[0-9]+(x|r[0-9]+))\b`
 const RE_COMPLEX_SPLIT = `(.*)([+-][jJ])(.*)`
 
-var MatchWhite = regexp.MustCompile(`^([ \t\r]*)`).FindStringSubmatch
+// White space includes a `#` comment up to (but not including) the newline.
+var MatchWhite = regexp.MustCompile(`^([ \t\r]*(#[^\n]*)?)`).FindStringSubmatch
 var MatchNumber = regexp.MustCompile(`^` + RE_REAL).FindStringSubmatch
+var MatchExact = regexp.MustCompile(`^` + RE_EXACT).FindStringSubmatch
 var MatchComplex = regexp.MustCompile(`^` + RE_COMPLEX).FindStringSubmatch
 var MatchComplexSplit = regexp.MustCompile(RE_COMPLEX_SPLIT).FindStringSubmatch
-var MatchVariable = regexp.MustCompile(`^([A-Z_][A-Za-z0-9_]*)`).FindStringSubmatch
+var MatchVariable = regexp.MustCompile(`^([A-Z_][A-Za-z0-9_]*|[$][A-Z][A-Za-z0-9_]*)`).FindStringSubmatch
 var MatchOperator = regexp.MustCompile("^" + RE_OPERATOR).FindStringSubmatch
 var MatchOpen = regexp.MustCompile(`^[(]`).FindStringSubmatch
 var MatchClose = regexp.MustCompile(`^[)]`).FindStringSubmatch
@@ -59,6 +64,10 @@ var MatchInnerProduct = regexp.MustCompile("^" + RE_JUST_OPERATOR + "[.]" + RE_J
 var MatchOuterProduct = regexp.MustCompile("^[.][.]" + RE_JUST_OPERATOR).FindStringSubmatch
 var MatchKeyword = regexp.MustCompile("^" + RE_KEYWORD).FindStringSubmatch
 
+// A lone `~` or `.` or `..` applies each or a product to a lambda.
+var MatchTilde = regexp.MustCompile(`^[~]`).FindStringSubmatch
+var MatchDot = regexp.MustCompile(`^[.][.]?`).FindStringSubmatch
+
 type Matcher struct {
 	Type    TokenType
 	MatchFn func(string) []string
@@ -67,6 +76,7 @@ type Matcher struct {
 var matchers = []Matcher{
 	{KeywordToken, MatchKeyword},
 	{ComplexToken, MatchComplex},
+	{NumberToken, MatchExact},
 	{NumberToken, MatchNumber},
 	{VariableToken, MatchVariable},
 	{ReduceToken, MatchReduce},
@@ -83,6 +93,8 @@ var matchers = []Matcher{
 	{CloseSquareToken, MatchCloseSquare},
 	{SemiToken, MatchSemi},
 	{StringToken, MatchString},
+	{TildeToken, MatchTilde},
+	{DotToken, MatchDot},
 }
 
 type Token struct {
@@ -120,10 +132,10 @@ func Tokenize(s string) *Lex {
 
 	llt := len(lex.Tokens)
 	if llt == 0 || lex.Tokens[llt-1].Type != EndToken {
-		Log.Panicf("Syntax error after %q before %q", s[:lex.p], s[lex.p:])
+		panicSourcef(SyntaxError, s, lex.p, "cannot tokenize after %q before %q", s[:lex.p], s[lex.p:])
 	}
 	if lex.p != len(s) {
-		Log.Panicf("Syntax error: did not parse all of %q: remaining part: %q", s, s[lex.p:])
+		panicSourcef(SyntaxError, s, lex.p, "did not tokenize all of %q: remaining part: %q", s, s[lex.p:])
 	}
 	//for i, t := range lex.Tokens {
 	//Log.Printf("Token [%d]: %s", i, t)
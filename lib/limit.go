@@ -0,0 +1,57 @@
+package livy
+
+import (
+	"sync/atomic"
+)
+
+const maxInt = int(^uint(0) >> 1)
+
+// hugeArraySize is more elements than any memory holds, so larger arrays are WS FULL, even without limits.
+const hugeArraySize = 1 << 40
+
+// Default limits set by UseDefaultLimits.  An element takes 16 to 32 bytes,
+// so the largest array takes about a gigabyte.
+const (
+	DefaultMaxArraySize = 1 << 25
+	DefaultMaxElements  = 1 << 30
+)
+
+// UseDefaultLimits sets MaxArraySize and MaxElements to their defaults,
+// so that asking for a huge array is a WS FULL ERROR, not a crash for lack of memory.
+// NewInterpreter and the command line use them.
+func (c *Context) UseDefaultLimits() {
+	c.MaxArraySize = DefaultMaxArraySize
+	c.MaxElements = DefaultMaxElements
+}
+
+// Alloc accounts for an array of n elements about to be made.
+// It raises a WS FULL ERROR if the array is larger than MaxArraySize,
+// or if it brings the elements made by this evaluation to more than MaxElements.
+// It may be called from any goroutine.
+func (c *Context) Alloc(n int) {
+	if int64(n) > hugeArraySize {
+		Panicf(WsFullError, "array of %d elements is too large", n)
+	}
+	if c.MaxArraySize > 0 && n > c.MaxArraySize {
+		Panicf(WsFullError, "array of %d elements is larger than the limit of %d", n, c.MaxArraySize)
+	}
+	if total := atomic.AddInt64(&c.elements, int64(n)); c.MaxElements > 0 && total > c.MaxElements {
+		Panicf(WsFullError, "more than %d elements made by one evaluation", c.MaxElements)
+	}
+}
+
+// AllocShape is Alloc for an array of the shape, returning its number of elements.
+func (c *Context) AllocShape(shape []int) int {
+	n := 1
+	for _, d := range shape {
+		if d < 0 {
+			Panicf(DomainError, "shape cannot be negative: %v", shape)
+		}
+		if d > 0 && n > maxInt/d {
+			Panicf(WsFullError, "array of shape %v is too large", shape)
+		}
+		n *= d
+	}
+	c.Alloc(n)
+	return n
+}
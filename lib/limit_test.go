@@ -0,0 +1,90 @@
+package livy
+
+import (
+	"testing"
+)
+
+func wantWsFull(t *testing.T, c *Context, src string) {
+	t.Helper()
+	_, err := c.EvalString(src)
+	if e, ok := err.(*LivyError); !ok || e.Kind != WsFullError {
+		t.Errorf("%s: got %v, wanted a WS FULL ERROR", src, err)
+	}
+}
+
+func TestMaxArraySize(t *testing.T) {
+	c := NewContext()
+	c.MaxArraySize = 1000
+	for _, src := range []string{
+		`iota 1001`,
+		`40 40 rho 0`,
+		`(iota 40) ..+ iota 40`,
+		`(iota 40) ..{ X + Y } iota 40`,
+		`(30 40 rho 1) +.* 40 50 rho 1`,
+		`(30 40 rho 1) +.{ X * Y } 40 50 rho 1`,
+		`1001 take 1 2 3`,
+		`(iota 600) , iota 600`,
+		`(iota 600) laminate iota 600`,
+		`(1001 rho 1) \ 1`,
+		`M = 30 30 rho 0 ; M[iota 30 ; 40 rho 0]`,
+		`2 1001 rho 0`,
+		`uniform 2000`,
+		`(40 rho 2) encode iota 40`,
+	} {
+		wantWsFull(t, c, src)
+	}
+
+	if got := evalIn(c, `+/ iota 1000`).String(); got != "499500 " {
+		t.Errorf("Got %s, wanted 499500", got)
+	}
+	if z, err := c.EvalString(`try iota 2000 catch E 5 end`); err != nil || z.String() != "5 " {
+		t.Errorf("Got %v, %v; try should catch WS FULL", z, err)
+	}
+}
+
+func TestMaxElements(t *testing.T) {
+	c := NewContext()
+	c.MaxElements = 100000
+	wantWsFull(t, c, `while 1 do A = 1000 rho 0 done`)
+	wantWsFull(t, c, `A = iota 60000 ; A + 1`)
+
+	c.MaxElements = 1000
+	wantWsFull(t, c, `X = 900 rho 1 ; +\ X`)
+	wantWsFull(t, c, `X = 900 rho 1 ; { X + Y }\ X`)
+	wantWsFull(t, c, `X = 2 450 rho 1 ; +\[0] X`)
+	c.MaxElements = 100000
+
+	// Each evaluation gets its own budget.
+	for i := 0; i < 3; i++ {
+		if _, err := c.EvalString(`A = iota 60000`); err != nil {
+			t.Errorf("Got %v", err)
+		}
+	}
+}
+
+func TestHugeShapes(t *testing.T) {
+	c := NewContext()
+	for _, src := range []string{
+		`1e9 1e9 1e9 rho 0`,
+		`iota 1e15`,
+		`1e15 deal 1e16`,
+	} {
+		wantWsFull(t, c, src)
+	}
+	if _, err := c.EvalString(`-2 3 rho 0`); err == nil {
+		t.Errorf("A negative shape should be an error")
+	}
+}
+
+func TestDefaultLimits(t *testing.T) {
+	in := NewInterpreter()
+	for _, src := range []string{`iota 1e10`, `1e5 1e5 rho 0`, `(iota 1e5) ..+ iota 1e5`} {
+		_, err := in.Eval(src)
+		if e, ok := err.(*LivyError); !ok || e.Kind != WsFullError {
+			t.Errorf("%s: got %v, wanted a WS FULL ERROR", src, err)
+		}
+	}
+	if z, err := in.Eval(`rho iota 100000`); err != nil || z.String() != "[1 ]{100000 } " {
+		t.Errorf("Got %v, %v", z, err)
+	}
+}
@@ -18,6 +18,18 @@ var StandardMonadics = map[string]MonadicFunc{
 	"s2b":   monadicS2B,
 	"b2s":   monadicB2S,
 
+	"format": monadicFormat,
+	"eval":   monadicEval,
+
+	"roll":    WrapMatMonadic(monadicRoll),
+	"exact":   WrapMatMonadicParallel(monadicExact),
+	"inexact": WrapMatMonadicParallel(monadicInexact),
+	"uniform": monadicUniform,
+	"normal":  monadicNormal,
+
+	"$nl": monadicNameList,
+	"$ex": monadicExpunge,
+
 	"up":        monadicUp,
 	"down":      monadicDown,
 	"transpose": transposeMonadic,
@@ -31,155 +43,155 @@ var StandardMonadics = map[string]MonadicFunc{
 	"i":  iotaMonadic,
 	"i1": iota1Monadic,
 	"p":  rhoMonadic,
-	"j": WrapMatMonadic(WrapCxMonadic(func(b complex128) complex128 {
+	"j": WrapMatCxMonadic(func(b complex128) complex128 {
 		return complex(0.0, 1.0) * b
-	})),
-	"real": WrapMatMonadic(WrapCxMonadic(func(b complex128) complex128 {
+	}),
+	"real": WrapMatCxMonadic(func(b complex128) complex128 {
 		return complex(real(b), 0.0)
-	})),
-	"imag": WrapMatMonadic(WrapCxMonadic(func(b complex128) complex128 {
+	}),
+	"imag": WrapMatCxMonadic(func(b complex128) complex128 {
 		return complex(imag(b), 0.0)
-	})),
-	"rect": WrapMatMonadic(WrapCxMonadic(func(b complex128) complex128 {
+	}),
+	"rect": WrapMatCxMonadic(func(b complex128) complex128 {
 		Must(imag(b) == 0)
 		return cmplx.Rect(1.0, real(b))
-	})),
-	"isInf": WrapMatMonadic(WrapCxMonadic(func(b complex128) complex128 {
+	}),
+	"isInf": WrapMatCxMonadic(func(b complex128) complex128 {
 		return Bool2Cx(cmplx.IsInf(b))
-	})),
-	"isNaN": WrapMatMonadic(WrapCxMonadic(func(b complex128) complex128 {
+	}),
+	"isNaN": WrapMatCxMonadic(func(b complex128) complex128 {
 		return Bool2Cx(cmplx.IsNaN(b))
-	})),
-	"asin": WrapMatMonadic(WrapCxMonadic(func(b complex128) complex128 {
+	}),
+	"asin": WrapMatCxMonadic(func(b complex128) complex128 {
 		return cmplx.Asin(b)
-	})),
-	"acos": WrapMatMonadic(WrapCxMonadic(func(b complex128) complex128 {
+	}),
+	"acos": WrapMatCxMonadic(func(b complex128) complex128 {
 		return cmplx.Acos(b)
-	})),
-	"atan": WrapMatMonadic(WrapCxMonadic(func(b complex128) complex128 {
+	}),
+	"atan": WrapMatCxMonadic(func(b complex128) complex128 {
 		return cmplx.Atan(b)
-	})),
-	"sin": WrapMatMonadic(WrapCxMonadic(func(b complex128) complex128 {
+	}),
+	"sin": WrapMatCxMonadic(func(b complex128) complex128 {
 		return cmplx.Sin(b)
-	})),
-	"cos": WrapMatMonadic(WrapCxMonadic(func(b complex128) complex128 {
+	}),
+	"cos": WrapMatCxMonadic(func(b complex128) complex128 {
 		return cmplx.Cos(b)
-	})),
-	"tan": WrapMatMonadic(WrapCxMonadic(func(b complex128) complex128 {
+	}),
+	"tan": WrapMatCxMonadic(func(b complex128) complex128 {
 		return cmplx.Tan(b)
-	})),
-	"asinh": WrapMatMonadic(WrapCxMonadic(func(b complex128) complex128 {
+	}),
+	"asinh": WrapMatCxMonadic(func(b complex128) complex128 {
 		return cmplx.Asinh(b)
-	})),
-	"acosh": WrapMatMonadic(WrapCxMonadic(func(b complex128) complex128 {
+	}),
+	"acosh": WrapMatCxMonadic(func(b complex128) complex128 {
 		return cmplx.Acosh(b)
-	})),
-	"atanh": WrapMatMonadic(WrapCxMonadic(func(b complex128) complex128 {
+	}),
+	"atanh": WrapMatCxMonadic(func(b complex128) complex128 {
 		return cmplx.Atanh(b)
-	})),
-	"sinh": WrapMatMonadic(WrapCxMonadic(func(b complex128) complex128 {
+	}),
+	"sinh": WrapMatCxMonadic(func(b complex128) complex128 {
 		return cmplx.Sinh(b)
-	})),
-	"cosh": WrapMatMonadic(WrapCxMonadic(func(b complex128) complex128 {
+	}),
+	"cosh": WrapMatCxMonadic(func(b complex128) complex128 {
 		return cmplx.Cosh(b)
-	})),
-	"tanh": WrapMatMonadic(WrapCxMonadic(func(b complex128) complex128 {
+	}),
+	"tanh": WrapMatCxMonadic(func(b complex128) complex128 {
 		return cmplx.Tanh(b)
-	})),
-	"exp": WrapMatMonadic(WrapCxMonadic(func(b complex128) complex128 {
+	}),
+	"exp": WrapMatCxMonadic(func(b complex128) complex128 {
 		return cmplx.Exp(b)
-	})),
-	"exp2": WrapMatMonadic(WrapFloatMonadic(func(b float64) float64 {
+	}),
+	"exp2": WrapMatFloatMonadic(func(b float64) float64 {
 		return math.Exp2(b)
-	})),
-	"expm1": WrapMatMonadic(WrapFloatMonadic(func(b float64) float64 {
+	}),
+	"expm1": WrapMatFloatMonadic(func(b float64) float64 {
 		return math.Expm1(b)
-	})),
-	"log": WrapMatMonadic(WrapCxMonadic(func(b complex128) complex128 {
+	}),
+	"log": WrapMatCxMonadic(func(b complex128) complex128 {
 		return cmplx.Log(b)
-	})),
-	"log10": WrapMatMonadic(WrapCxMonadic(func(b complex128) complex128 {
+	}),
+	"log10": WrapMatCxMonadic(func(b complex128) complex128 {
 		return cmplx.Log10(b)
-	})),
-	"log2": WrapMatMonadic(WrapFloatMonadic(func(b float64) float64 {
+	}),
+	"log2": WrapMatFloatMonadic(func(b float64) float64 {
 		return math.Log2(b)
-	})),
-	"log1p": WrapMatMonadic(WrapFloatMonadic(func(b float64) float64 {
+	}),
+	"log1p": WrapMatFloatMonadic(func(b float64) float64 {
 		return math.Log1p(b)
+	}),
+	"ceil": WrapMatMonadicParallel(WrapExactMonadic(exactCeil, func(c *Context, b Val, axis int) Val {
+		return FloatNum(c.Ceil(b.GetScalarFloat()))
 	})),
-	"ceil": WrapMatMonadic(WrapFloatMonadic(func(b float64) float64 {
-		return math.Ceil(b)
+	"floor": WrapMatMonadicParallel(WrapExactMonadic(exactFloor, func(c *Context, b Val, axis int) Val {
+		return FloatNum(c.Floor(b.GetScalarFloat()))
 	})),
-	"floor": WrapMatMonadic(WrapFloatMonadic(func(b float64) float64 {
-		return math.Floor(b)
-	})),
-	"round": WrapMatMonadic(WrapCxMonadic(func(b complex128) complex128 {
+	"round": WrapMatCxMonadic(func(b complex128) complex128 {
 		return complex(math.Round(real(b)), math.Round(imag(b)))
-	})),
-	"round1": WrapMatMonadic(WrapCxMonadic(func(b complex128) complex128 {
+	}),
+	"round1": WrapMatCxMonadic(func(b complex128) complex128 {
 		return complex(math.Round(real(10*b))/10, math.Round(imag(10*b))/10)
-	})),
-	"round2": WrapMatMonadic(WrapCxMonadic(func(b complex128) complex128 {
+	}),
+	"round2": WrapMatCxMonadic(func(b complex128) complex128 {
 		return complex(math.Round(real(100*b))/100, math.Round(imag(100*b))/100)
-	})),
-	"round3": WrapMatMonadic(WrapCxMonadic(func(b complex128) complex128 {
+	}),
+	"round3": WrapMatCxMonadic(func(b complex128) complex128 {
 		return complex(math.Round(real(1000*b))/1000, math.Round(imag(1000*b))/1000)
-	})),
-	"round4": WrapMatMonadic(WrapCxMonadic(func(b complex128) complex128 {
+	}),
+	"round4": WrapMatCxMonadic(func(b complex128) complex128 {
 		return complex(math.Round(real(10000*b))/10000, math.Round(imag(10000*b))/10000)
-	})),
-	"round5": WrapMatMonadic(WrapCxMonadic(func(b complex128) complex128 {
+	}),
+	"round5": WrapMatCxMonadic(func(b complex128) complex128 {
 		return complex(math.Round(real(100000*b))/100000, math.Round(imag(100000*b))/100000)
-	})),
-	"round6": WrapMatMonadic(WrapCxMonadic(func(b complex128) complex128 {
+	}),
+	"round6": WrapMatCxMonadic(func(b complex128) complex128 {
 		return complex(math.Round(real(1000000*b))/1000000, math.Round(imag(1000000*b))/10000000)
-	})),
-	"round7": WrapMatMonadic(WrapCxMonadic(func(b complex128) complex128 {
+	}),
+	"round7": WrapMatCxMonadic(func(b complex128) complex128 {
 		return complex(math.Round(real(10000000*b))/10000000, math.Round(imag(10000000*b))/100000000)
-	})),
-	"round8": WrapMatMonadic(WrapCxMonadic(func(b complex128) complex128 {
+	}),
+	"round8": WrapMatCxMonadic(func(b complex128) complex128 {
 		return complex(math.Round(real(100000000*b))/100000000, math.Round(imag(100000000*b))/1000000000)
-	})),
-	"round9": WrapMatMonadic(WrapCxMonadic(func(b complex128) complex128 {
+	}),
+	"round9": WrapMatCxMonadic(func(b complex128) complex128 {
 		return complex(math.Round(real(1000000000*b))/1000000000, math.Round(imag(1000000000*b))/10000000000)
-	})),
-	"roundToEven": WrapMatMonadic(WrapFloatMonadic(func(b float64) float64 {
+	}),
+	"roundToEven": WrapMatFloatMonadic(func(b float64) float64 {
 		return math.RoundToEven(b)
-	})),
-	"ki":     WrapMatMonadic(WrapCxMonadic(func(b complex128) complex128 { return 1024 * b })),
-	"mi":     WrapMatMonadic(WrapCxMonadic(func(b complex128) complex128 { return 1024 * 1024 * b })),
-	"gi":     WrapMatMonadic(WrapCxMonadic(func(b complex128) complex128 { return 1024 * 1024 * 1024 * b })),
-	"ti":     WrapMatMonadic(WrapCxMonadic(func(b complex128) complex128 { return 1024 * 1024 * 1024 * 1024 * b })),
-	"pi":     WrapMatMonadic(WrapCxMonadic(func(b complex128) complex128 { return 1024 * 1024 * 1024 * 1024 * 1024 * b })),
-	"ei":     WrapMatMonadic(WrapCxMonadic(func(b complex128) complex128 { return 1024 * 1024 * 1024 * 1024 * 1024 * 1024 * b })),
-	"ks":     WrapMatMonadic(WrapCxMonadic(func(b complex128) complex128 { return 1000 * b })),
-	"ms":     WrapMatMonadic(WrapCxMonadic(func(b complex128) complex128 { return 1000 * 1000 * b })),
-	"gs":     WrapMatMonadic(WrapCxMonadic(func(b complex128) complex128 { return 1000 * 1000 * 1000 * b })),
-	"ts":     WrapMatMonadic(WrapCxMonadic(func(b complex128) complex128 { return 1000 * 1000 * 1000 * 1000 * b })),
-	"ps":     WrapMatMonadic(WrapCxMonadic(func(b complex128) complex128 { return 1000 * 1000 * 1000 * 1000 * 1000 * b })),
-	"es":     WrapMatMonadic(WrapCxMonadic(func(b complex128) complex128 { return 1000 * 1000 * 1000 * 1000 * 1000 * 1000 * b })),
-	"millis": WrapMatMonadic(WrapCxMonadic(func(b complex128) complex128 { return b / 1000 })),
-	"micros": WrapMatMonadic(WrapCxMonadic(func(b complex128) complex128 { return b / 1000 / 1000 })),
-	"nanos":  WrapMatMonadic(WrapCxMonadic(func(b complex128) complex128 { return b / 1000 / 1000 / 1000 })),
-	"picos":  WrapMatMonadic(WrapCxMonadic(func(b complex128) complex128 { return b / 1000 / 1000 / 1000 / 1000 })),
-	"div":    WrapMatMonadic(WrapCxMonadic(func(b complex128) complex128 { return 1 / b })),
-	"cbrt": WrapMatMonadic(WrapCxMonadic(func(b complex128) complex128 {
+	}),
+	"ki":     WrapMatCxMonadic(func(b complex128) complex128 { return 1024 * b }),
+	"mi":     WrapMatCxMonadic(func(b complex128) complex128 { return 1024 * 1024 * b }),
+	"gi":     WrapMatCxMonadic(func(b complex128) complex128 { return 1024 * 1024 * 1024 * b }),
+	"ti":     WrapMatCxMonadic(func(b complex128) complex128 { return 1024 * 1024 * 1024 * 1024 * b }),
+	"pi":     WrapMatCxMonadic(func(b complex128) complex128 { return 1024 * 1024 * 1024 * 1024 * 1024 * b }),
+	"ei":     WrapMatCxMonadic(func(b complex128) complex128 { return 1024 * 1024 * 1024 * 1024 * 1024 * 1024 * b }),
+	"ks":     WrapMatCxMonadic(func(b complex128) complex128 { return 1000 * b }),
+	"ms":     WrapMatCxMonadic(func(b complex128) complex128 { return 1000 * 1000 * b }),
+	"gs":     WrapMatCxMonadic(func(b complex128) complex128 { return 1000 * 1000 * 1000 * b }),
+	"ts":     WrapMatCxMonadic(func(b complex128) complex128 { return 1000 * 1000 * 1000 * 1000 * b }),
+	"ps":     WrapMatCxMonadic(func(b complex128) complex128 { return 1000 * 1000 * 1000 * 1000 * 1000 * b }),
+	"es":     WrapMatCxMonadic(func(b complex128) complex128 { return 1000 * 1000 * 1000 * 1000 * 1000 * 1000 * b }),
+	"millis": WrapMatCxMonadic(func(b complex128) complex128 { return b / 1000 }),
+	"micros": WrapMatCxMonadic(func(b complex128) complex128 { return b / 1000 / 1000 }),
+	"nanos":  WrapMatCxMonadic(func(b complex128) complex128 { return b / 1000 / 1000 / 1000 }),
+	"picos":  WrapMatCxMonadic(func(b complex128) complex128 { return b / 1000 / 1000 / 1000 / 1000 }),
+	"div":    WrapMatExactCxMonadic(exactRecip, func(b complex128) complex128 { return 1 / b }),
+	"cbrt": WrapMatCxMonadic(func(b complex128) complex128 {
 		if imag(b) == 0 {
 			return complex(math.Cbrt(real(b)), 0)
 		} else {
 			return cmplx.Pow(b, complex(1.0/3.0, 0))
 		}
-	})),
-	"sqrt": WrapMatMonadic(WrapCxMonadic(func(b complex128) complex128 {
+	}),
+	"sqrt": WrapMatCxMonadic(func(b complex128) complex128 {
 		return cmplx.Sqrt(b)
-	})),
-	"double": WrapMatMonadic(WrapCxMonadic(func(b complex128) complex128 {
+	}),
+	"double": WrapMatCxMonadic(func(b complex128) complex128 {
 		return b + b
-	})),
-	"square": WrapMatMonadic(WrapCxMonadic(func(b complex128) complex128 {
+	}),
+	"square": WrapMatCxMonadic(func(b complex128) complex128 {
 		return b * b
-	})),
-	"sgn": WrapMatMonadic(WrapFloatMonadic(func(b float64) float64 {
+	}),
+	"sgn": WrapMatExactFloatMonadic(exactSgn, func(b float64) float64 {
 		if b < 0 {
 			return -1
 		} else if b > 0 {
@@ -189,53 +201,53 @@ var StandardMonadics = map[string]MonadicFunc{
 		} else {
 			panic("cannot sgn")
 		}
-	})),
-	"abs": WrapMatMonadic(WrapCxMonadic(func(b complex128) complex128 {
+	}),
+	"abs": WrapMatExactCxMonadic(exactAbs, func(b complex128) complex128 {
 		return complex(cmplx.Abs(b), 0)
-	})),
-	"phase": WrapMatMonadic(WrapCxMonadic(func(b complex128) complex128 {
+	}),
+	"phase": WrapMatCxMonadic(func(b complex128) complex128 {
 		return complex(cmplx.Phase(b), 0.0)
-	})),
-	"erf": WrapMatMonadic(WrapFloatMonadic(func(b float64) float64 {
+	}),
+	"erf": WrapMatFloatMonadic(func(b float64) float64 {
 		return math.Erf(b)
-	})),
-	"erfc": WrapMatMonadic(WrapFloatMonadic(func(b float64) float64 {
+	}),
+	"erfc": WrapMatFloatMonadic(func(b float64) float64 {
 		return math.Erfc(b)
-	})),
-	"erfinv": WrapMatMonadic(WrapFloatMonadic(func(b float64) float64 {
+	}),
+	"erfinv": WrapMatFloatMonadic(func(b float64) float64 {
 		return math.Erfinv(b)
-	})),
-	"erfcinv": WrapMatMonadic(WrapFloatMonadic(func(b float64) float64 {
+	}),
+	"erfcinv": WrapMatFloatMonadic(func(b float64) float64 {
 		return math.Erfcinv(b)
-	})),
-	"gamma": WrapMatMonadic(WrapFloatMonadic(func(b float64) float64 {
+	}),
+	"gamma": WrapMatFloatMonadic(func(b float64) float64 {
 		return math.Gamma(b)
-	})),
-	"inf": WrapMatMonadic(WrapFloatMonadic(func(b float64) float64 {
+	}),
+	"inf": WrapMatFloatMonadic(func(b float64) float64 {
 		return math.Inf(int(b))
-	})),
-	"y0": WrapMatMonadic(WrapFloatMonadic(func(b float64) float64 {
+	}),
+	"y0": WrapMatFloatMonadic(func(b float64) float64 {
 		return math.Y0(b)
-	})),
-	"y1": WrapMatMonadic(WrapFloatMonadic(func(b float64) float64 {
+	}),
+	"y1": WrapMatFloatMonadic(func(b float64) float64 {
 		return math.Y1(b)
-	})),
-	"neg": WrapMatMonadic(WrapCxMonadic(func(b complex128) complex128 {
+	}),
+	"neg": WrapMatExactCxMonadic(exactNeg, func(b complex128) complex128 {
 		return -b
-	})),
-	"-": WrapMatMonadic(WrapCxMonadic(func(b complex128) complex128 {
+	}),
+	"-": WrapMatExactCxMonadic(exactNeg, func(b complex128) complex128 {
 		return -b
-	})),
-	"+": WrapMatMonadic(WrapCxMonadic(func(b complex128) complex128 {
+	}),
+	"+": WrapMatExactCxMonadic(func(b Exact) Val { return &b }, func(b complex128) complex128 {
 		return +b
-	})),
-	"conjugate": WrapMatMonadic(WrapCxMonadic(func(b complex128) complex128 {
+	}),
+	"conjugate": WrapMatCxMonadic(func(b complex128) complex128 {
 		return complex(real(b), -imag(b))
-	})),
-	"not": WrapMatMonadic(WrapFloatMonadic(func(b float64) float64 {
+	}),
+	"not": WrapMatFloatMonadic(func(b float64) float64 {
 		x := float2bool(b)
 		return boolf(!x)
-	})),
+	}),
 }
 
 func boolf(a bool) float64 {
@@ -250,13 +262,13 @@ func MkEachOpMonadic(name string, fn MonadicFunc) MonadicFunc {
 	return func(c *Context, b Val, axis int) Val {
 		mat, ok := b.(*Mat)
 		if !ok {
-			Log.Panicf("Each operator %s~ expects matrix argument, got %s", name, b)
+			Panicf(DomainError, "Each operator %s~ expects matrix argument, got %s", name, b)
 		}
-		vec := make([]Val, len(mat.M))
-		for i, x := range mat.M {
+		vec := make([]Val, mat.Len())
+		for i, x := range mat.Vals() {
 			vec[i] = fn(c, x, axis)
 		}
-		return &Mat{vec, mat.S}
+		return &Mat{M: vec, S: mat.S}
 	}
 }
 
@@ -282,7 +294,7 @@ func doubleMonadic(c *Context, b Val) Val {
 	case *Num:
 		return &Num{2 * y.F}
 	}
-	Log.Panicf("Wrong type for monadic `double`: %T %q", b, b)
+	Panicf(DomainError, "Wrong type for monadic `double`: %T %q", b, b)
 	return nil
 }
 
@@ -290,10 +302,10 @@ func ravelMonadic(c *Context, b Val, axis int) Val {
 	mat, ok := b.(*Mat)
 	if ok {
 		// Grab ravelled guts from the matrix.
-		return &Mat{mat.M, []int{len(mat.M)}}
+		return &Mat{M: mat.M, S: []int{mat.Len()}, P: mat.P}
 	}
 	// Singleton vector.
-	return &Mat{[]Val{b}, []int{1}}
+	return &Mat{M: []Val{b}, S: []int{1}}
 }
 
 func iotaMonadic(c *Context, b Val, axis int) Val {
@@ -303,15 +315,18 @@ func iota1Monadic(c *Context, b Val, axis int) Val {
 	return iotaK(c, b, 1)
 }
 func iotaK(c *Context, b Val, k int) Val {
-	n := b.GetScalarInt()
-	vec := make([]Val, n)
-	for i := 0; i < n; i++ {
-		vec[i] = &Num{complex(float64(i+k), 0)}
-	}
-	return &Mat{
-		M: vec,
-		S: []int{n},
+	n := c.ScalarInt(b)
+	if n < 0 {
+		Panicf(DomainError, "iota of negative %d", n)
 	}
+	c.Alloc(n)
+	vec := make(Ints, n)
+	c.parallelFor(n, func(lo, hi int) {
+		for i := lo; i < hi; i++ {
+			vec[i] = int64(i + k)
+		}
+	})
+	return PackedMat(vec, []int{n})
 }
 
 func rhoMonadic(c *Context, b Val, axis int) Val {
@@ -334,19 +349,90 @@ func rhoMonadic(c *Context, b Val, axis int) Val {
 	}
 }
 
+// WrapMatCxMonadic is WrapMatMonadicParallel(WrapCxMonadic(fn)),
+// with a fast path for packed numeric arrays.
+func WrapMatCxMonadic(fn funcCxCx) MonadicFunc {
+	return withPackedCxMonadic(WrapMatMonadicParallel(WrapCxMonadic(fn)), fn)
+}
+
+// WrapMatExactCxMonadic is like WrapMatCxMonadic, but uses exact for Exact numbers.
+func WrapMatExactCxMonadic(exact FuncExactVal, fn funcCxCx) MonadicFunc {
+	return withPackedCxMonadic(WrapMatMonadicParallel(WrapExactMonadic(exact, WrapCxMonadic(fn))), fn)
+}
+
+func withPackedCxMonadic(general MonadicFunc, fn funcCxCx) MonadicFunc {
+	kernel := func(x complex128) (complex128, bool) {
+		return fn(x), true
+	}
+	return func(c *Context, b Val, axis int) Val {
+		if z := packedMonadic(c, b, kernel); z != nil {
+			return z
+		}
+		return general(c, b, axis)
+	}
+}
+
+// WrapMatFloatMonadic is WrapMatMonadicParallel(WrapFloatMonadic(fn)),
+// with a fast path for packed real arrays.
+func WrapMatFloatMonadic(fn funcFloatFloat) MonadicFunc {
+	return withPackedFloatMonadic(WrapMatMonadicParallel(WrapFloatMonadic(fn)), fn)
+}
+
+// WrapMatExactFloatMonadic is like WrapMatFloatMonadic, but uses exact for Exact numbers.
+func WrapMatExactFloatMonadic(exact FuncExactVal, fn funcFloatFloat) MonadicFunc {
+	return withPackedFloatMonadic(WrapMatMonadicParallel(WrapExactMonadic(exact, WrapFloatMonadic(fn))), fn)
+}
+
+func withPackedFloatMonadic(general MonadicFunc, fn funcFloatFloat) MonadicFunc {
+	kernel := func(x complex128) (complex128, bool) {
+		if imag(x) != 0 {
+			return 0, false
+		}
+		return complex(fn(real(x)), 0), true
+	}
+	return func(c *Context, b Val, axis int) Val {
+		if z := packedMonadic(c, b, kernel); z != nil {
+			return z
+		}
+		return general(c, b, axis)
+	}
+}
+
+// WrapMatMonadic makes a scalar function work on every element of an array,
+// calling fn on the elements in order, in one goroutine.
 func WrapMatMonadic(fn MonadicFunc) MonadicFunc {
+	return wrapMatMonadic(fn, false)
+}
+
+// WrapMatMonadicParallel is like WrapMatMonadic, but for large arrays,
+// calls fn from several goroutines at once, so fn must be safe for that.
+// The standard scalar functions use it.
+func WrapMatMonadicParallel(fn MonadicFunc) MonadicFunc {
+	return wrapMatMonadic(fn, true)
+}
+
+func wrapMatMonadic(fn MonadicFunc, parallel bool) MonadicFunc {
 	return func(c *Context, b Val, axis int) Val {
 		switch y := b.(type) {
 		case *Mat:
-			n := len(y.M)
+			vals := y.Vals()
+			n := len(vals)
+			c.Alloc(n)
 			vec := make([]Val, n)
 
-			for i := 0; i < n; i++ {
-				y1 := y.M[i].GetScalarOrNil()
-				if y1 == nil {
-					Log.Panicf("arg not a scalar at matrix offset %d: %s", i, y1)
+			each := func(lo, hi int) {
+				for i := lo; i < hi; i++ {
+					y1 := vals[i].GetScalarOrNil()
+					if y1 == nil {
+						Panicf(DomainError, "arg not a scalar at matrix offset %d: %s", i, y1)
+					}
+					vec[i] = fn(c, y1, axis)
 				}
-				vec[i] = fn(c, y1, axis)
+			}
+			if parallel {
+				c.parallelFor(n, each)
+			} else {
+				each(0, n)
 			}
 
 			return &Mat{M: vec, S: y.S}
@@ -355,7 +441,7 @@ func WrapMatMonadic(fn MonadicFunc) MonadicFunc {
 
 		ys := b.GetScalarOrNil()
 		if ys == nil {
-			Log.Panicf("arg not scalar or matrix")
+			Panicf(DomainError, "arg not scalar or matrix")
 		}
 		return fn(c, ys, axis)
 	}
@@ -364,19 +450,19 @@ func WrapMatMonadic(fn MonadicFunc) MonadicFunc {
 func rotMonadic(c *Context, b Val, axis int) Val {
 	mat, ok := b.(*Mat)
 	if !ok {
-		Log.Panicf("monadic rot: Cannot reverse a non-matrix")
+		Panicf(DomainError, "monadic rot: Cannot reverse a non-matrix")
 	}
 
 	shape := mat.S
 	rank := len(shape)
-	if len(mat.M) < 2 {
+	if mat.Len() < 2 {
 		return b
 	}
 	axis = Mod(axis, rank)
 	axisLen := shape[axis]
 	revaxis := rank - axis
 
-	inVec := mat.M
+	inVec := mat.Vals()
 	var outVec []Val
 
 	var recurse func(shape []int, inOff int)
@@ -410,13 +496,13 @@ func rotMonadic(c *Context, b Val, axis int) Val {
 func transposeMonadic(c *Context, b Val, axis int) Val {
 	mat, ok := b.(*Mat)
 	if !ok {
-		Log.Panicf("Monadic `transpose` needs matrix on right, but got %v", b)
+		Panicf(DomainError, "Monadic `transpose` needs matrix on right, but got %v", b)
 	}
 
 	shape := mat.S
 	rank := len(shape)
 	if rank < 2 {
-		Log.Panicf("Monadic `transpose` needs matrix with rank >= 2, but got shape %v", shape)
+		Panicf(RankError, "Monadic `transpose` needs matrix with rank >= 2, but got shape %v", shape)
 	}
 
 	var spec []Val
@@ -430,113 +516,159 @@ func transposeMonadic(c *Context, b Val, axis int) Val {
 			spec = append(spec, &Num{complex(float64(Mod(i, rank)), 0)})
 		}
 	}
-	lhs := &Mat{spec, []int{len(spec)}}
+	lhs := &Mat{M: spec, S: []int{len(spec)}}
 
 	return dyadicTranspose(c, lhs, b, -1)
 }
 
-type IndexedValSlice struct {
-	Vals []Val
-	Ints []int
-}
-
-func (p IndexedValSlice) Len() int {
-	return len(p.Vals)
-}
-
-func (p IndexedValSlice) Less(i, j int) bool {
-	return Compare(p.Vals[p.Ints[i]], p.Vals[p.Ints[j]]) < 0
-}
-
-func (p *IndexedValSlice) Swap(i, j int) {
-	p.Ints[i], p.Ints[j] = p.Ints[j], p.Ints[i]
-}
-
 func monadicUp(c *Context, b Val, axis int) Val {
 	return monadicUpDown(c, b, false, "up")
 }
 func monadicDown(c *Context, b Val, axis int) Val {
 	return monadicUpDown(c, b, true, "down")
 }
+
+// monadicUpDown grades the major cells of the matrix (the items along its first axis),
+// comparing cells element by element.  The sort is stable, so equal cells keep their order.
 func monadicUpDown(c *Context, b Val, reverse bool, name string) Val {
 	mat, ok := b.(*Mat)
 	if !ok {
-		Log.Panicf("monadic `%s` wants matrix, got %v", name, b)
+		Panicf(DomainError, "monadic `%s` wants matrix, got %v", name, b)
 	}
-	if len(mat.S) != 1 {
-		Log.Panicf("monadic `%s` wants matrix of rank 1, got %v", name, b)
+	if len(mat.S) == 0 {
+		Panicf(RankError, "monadic `%s` wants matrix of rank 1 or more, got %v", name, b)
 	}
 
 	n := mat.S[0]
+	vec := mat.Vals()
+	cellSize := Product(mat.S[1:])
+	compareCells := func(i, j int) int {
+		for k := 0; k < cellSize; k++ {
+			cmp := Compare(vec[i*cellSize+k], vec[j*cellSize+k])
+			if cmp != 0 {
+				return cmp
+			}
+		}
+		return 0
+	}
+
 	ints := make([]int, n)
 	for i := range ints {
 		ints[i] = i
 	}
-
-	sorter := &IndexedValSlice{Vals: mat.M, Ints: ints}
-	sort.Sort(sorter)
-
-	var outVec []Val
-	for i := 0; i < n; i++ {
-		j := i
+	sort.SliceStable(ints, func(i, j int) bool {
+		cmp := compareCells(ints[i], ints[j])
 		if reverse {
-			j = n - 1 - i
+			return cmp > 0
 		}
-		outVec = append(outVec, &Num{complex(float64(ints[j]), 0)})
-	}
+		return cmp < 0
+	})
 
-	return &Mat{outVec, []int{n}}
+	outVec := make([]Val, n)
+	for i, j := range ints {
+		outVec[i] = &Num{complex(float64(j), 0)}
+	}
+	return &Mat{M: outVec, S: []int{n}}
 }
+
+// b2s converts a vector of byte codes (UTF-8) to a char vector.
 func monadicB2S(c *Context, b Val, axis int) Val {
 	mat, ok := b.(*Mat)
 	if !ok {
-		Log.Panicf("b2s: Not a matrix")
+		Panicf(DomainError, "b2s: Not a matrix")
 	}
 	if len(mat.S) != 1 {
-		Log.Panicf("b2s: Not a matrix of rank 1")
+		Panicf(RankError, "b2s: Not a matrix of rank 1")
 	}
 	n := mat.S[0]
 	var bb bytes.Buffer
 	for i := 0; i < n; i++ {
-		x := mat.M[i].GetScalarInt()
+		x := c.ScalarInt(mat.Vals()[i])
 		if x < 0 || x > 255 {
-			Log.Panicf("b2s: not a byte: %d", x)
+			Panicf(DomainError, "b2s: not a byte: %d", x)
 		}
 		bb.WriteByte(byte(x & 255))
 	}
-	return &Box{bb.String()}
+	return StringMat(bb.String())
 }
-func monadicS2B(c *Context, b Val, axis int) Val {
-	box, ok := b.(*Box)
-	if !ok {
-		Log.Panicf("s2b: Not a string in a box, not even a box")
-	}
-	str, ok := box.X.(string)
+
+// s2b converts a char vector (or a string in a box) to a vector of byte codes (UTF-8).
+// stringArg gets the text of a char vector, or of a string (or Stringer) in a box.
+func stringArg(name string, b Val) string {
+	str, ok := GetString(b)
 	if !ok {
-		s, ok := box.X.(fmt.Stringer)
+		box, ok := b.(*Box)
 		if !ok {
-			Log.Panicf("s2b: Not a string (or a Stringer) in a box")
+			Panicf(DomainError, "%s: Not a char vector, nor a string in a box", name)
+		}
+		if v, isVal := box.X.(Val); isVal {
+			str, ok = GetString(v)
+		} else {
+			str, ok = box.X.(string)
+		}
+		if !ok {
+			s, ok := box.X.(fmt.Stringer)
+			if !ok {
+				Panicf(DomainError, "%s: Not a string (or a Stringer) in a box", name)
+			}
+			str = s.String()
 		}
-		str = s.String()
 	}
+	return str
+}
+
+func monadicS2B(c *Context, b Val, axis int) Val {
+	str := stringArg("s2b", b)
 	n := len(str)
 	z := make([]Val, n)
 	for i := 0; i < n; i++ {
 		z[i] = IntNum(int(str[i]))
 	}
-	return &Mat{z, []int{n}}
+	return &Mat{M: z, S: []int{n}}
 }
+
+// monadicEval executes the text as source in the current context,
+// so it can use the locals of the calling function.
+func monadicEval(c *Context, b Val, axis int) Val {
+	src := stringArg("eval", b)
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+		if e := AsLivyError(r); e != nil {
+			// Positions in errors refer to the evaluated text.
+			if e.Source == "" && e.Pos >= 0 {
+				e.Source = src
+			}
+			panic(e)
+		}
+		panic(r)
+	}()
+
+	lex := Tokenize(src)
+	p := &Parser{Context: c}
+	seq, i := p.ParseSeq(lex, 0)
+	if t := lex.Tokens[i]; t.Type != EndToken {
+		panicSourcef(SyntaxError, src, t.Pos, "unexpected %q", t.Str)
+	}
+	if len(seq.Vec) == 0 {
+		return &Mat{M: nil, S: []int{0}}
+	}
+	return seq.Eval(c)
+}
+
 func monadicBox(c *Context, b Val, axis int) Val {
 	return &Box{b}
 }
 func monadicUnbox(c *Context, b Val, axis int) Val {
 	box, ok := b.(*Box)
 	if !ok {
-		Log.Panicf("In unbox, not a box: %T: %v", b, b)
+		Panicf(DomainError, "In unbox, not a box: %T: %v", b, b)
 	}
 	val, ok := box.X.(Val)
 	if !ok {
-		Log.Panicf("In unbox, not an apl value in the box: %T: %v", box.X, box.X)
+		Panicf(DomainError, "In unbox, not an apl value in the box: %T: %v", box.X, box.X)
 	}
 	return val
 }
@@ -0,0 +1,358 @@
+package livy
+
+import (
+	"math"
+	"sync/atomic"
+)
+
+// Packed is compact, typed storage for the elements of a numeric Mat.
+// A Mat with P set has a nil M until something needs its elements as Vals,
+// so large numeric arrays need not hold a *Num for every element.
+//
+// Numbers are packed in the narrowest type that holds them all exactly:
+// Bits for 0s and 1s, Ints for integers, Floats for other reals, and Cxs.
+type Packed interface {
+	Len() int
+	Cx(i int) complex128
+}
+
+// Bits holds 0s and 1s, one bit each.
+type Bits struct {
+	W []uint64
+	N int
+}
+
+// Ints holds integers that a float64 represents exactly.
+type Ints []int64
+
+// Floats holds real numbers.
+type Floats []float64
+
+// Cxs holds complex numbers.
+type Cxs []complex128
+
+func (o Bits) Len() int { return o.N }
+func (o Bits) Cx(i int) complex128 {
+	if (o.W[i>>6]>>uint(i&63))&1 != 0 {
+		return 1
+	}
+	return 0
+}
+
+func (o Ints) Len() int              { return len(o) }
+func (o Ints) Cx(i int) complex128   { return complex(float64(o[i]), 0) }
+func (o Floats) Len() int            { return len(o) }
+func (o Floats) Cx(i int) complex128 { return complex(o[i], 0) }
+func (o Cxs) Len() int               { return len(o) }
+func (o Cxs) Cx(i int) complex128    { return o[i] }
+
+// maxPackedInt is the largest magnitude of integer packed as Ints.
+const maxPackedInt = 1 << 53
+
+const (
+	bitsKind = iota
+	intsKind
+	floatsKind
+	cxsKind
+)
+
+// packKind finds the narrowest kind of storage that holds x.
+// Negative zero is not an integer here, so its sign is kept.
+func packKind(x complex128) int {
+	re := real(x)
+	switch {
+	case imag(x) != 0:
+		return cxsKind
+	case (re == 0 && !math.Signbit(re)) || re == 1:
+		return bitsKind
+	case re == math.Trunc(re) && math.Abs(re) <= maxPackedInt && re != 0:
+		return intsKind
+	}
+	return floatsKind
+}
+
+// PackCxs stores numbers in the narrowest Packed type that holds them all exactly.
+func PackCxs(xs []complex128) Packed {
+	kind := bitsKind
+	for _, x := range xs {
+		if k := packKind(x); k > kind {
+			kind = k
+			if kind == cxsKind {
+				break
+			}
+		}
+	}
+
+	switch kind {
+	case bitsKind:
+		z := Bits{W: make([]uint64, (len(xs)+63)/64), N: len(xs)}
+		for i, x := range xs {
+			if real(x) != 0 {
+				z.W[i>>6] |= 1 << uint(i&63)
+			}
+		}
+		return z
+	case intsKind:
+		z := make(Ints, len(xs))
+		for i, x := range xs {
+			z[i] = int64(real(x))
+		}
+		return z
+	case floatsKind:
+		z := make(Floats, len(xs))
+		for i, x := range xs {
+			z[i] = real(x)
+		}
+		return z
+	}
+	return Cxs(xs)
+}
+
+// PackVals packs the elements if they are all numbers, or returns nil.
+func PackVals(vec []Val) Packed {
+	xs := make([]complex128, len(vec))
+	for i, v := range vec {
+		switch t := v.(type) {
+		case *Num:
+			xs[i] = t.F
+		case Num:
+			xs[i] = t.F
+		default:
+			return nil
+		}
+	}
+	return PackCxs(xs)
+}
+
+// unpack makes Vals of the packed numbers, sharing one allocation for all the Nums.
+func unpack(p Packed) []Val {
+	n := p.Len()
+	nums := make([]Num, n)
+	vec := make([]Val, n)
+	for i := range nums {
+		nums[i].F = p.Cx(i)
+		vec[i] = &nums[i]
+	}
+	return vec
+}
+
+// PackedMat makes a Mat of the shape with packed elements.
+func PackedMat(p Packed, shape []int) *Mat {
+	return &Mat{S: shape, P: p}
+}
+
+// Vals returns the elements, unpacking them if needed.
+func (o *Mat) Vals() []Val {
+	if o.M == nil && o.P != nil {
+		o.M = unpack(o.P)
+	}
+	return o.M
+}
+
+// Len is the number of elements, without unpacking them.
+func (o *Mat) Len() int {
+	if o.M == nil && o.P != nil {
+		return o.P.Len()
+	}
+	return len(o.M)
+}
+
+// packedOf returns the numbers of a numeric Mat or a number as Packed storage,
+// or nil for other values.
+func packedOf(v Val) Packed {
+	switch t := v.(type) {
+	case *Mat:
+		if t.P != nil {
+			return t.P
+		}
+		return PackVals(t.M)
+	case *Num:
+		return Cxs{t.F}
+	case Num:
+		return Cxs{t.F}
+	}
+	return nil
+}
+
+// packedResult makes a packed Mat of the shape, or a *Num if the shape is empty.
+func packedResult(shape []int, xs []complex128) Val {
+	if len(shape) == 0 {
+		return &Num{xs[0]}
+	}
+	return PackedMat(PackCxs(xs), shape)
+}
+
+// cxKernel is a scalar function on the numbers of packed arrays.
+// It returns ok==false if it cannot handle the numbers,
+// so the caller must take the general path, which reports the error.
+type cxKernel func(x, y complex128) (z complex128, ok bool)
+
+// packedDyadic applies a kernel to conforming numeric arguments, at least one of them a Mat,
+// without making a Val for each element.  It returns nil if it cannot.
+func packedDyadic(c *Context, a, b Val, kernel cxKernel) Val {
+	_, aMat := a.(*Mat)
+	_, bMat := b.(*Mat)
+	if !aMat && !bMat {
+		return nil
+	}
+	pa := packedOf(a)
+	if pa == nil {
+		return nil
+	}
+	pb := packedOf(b)
+	if pb == nil {
+		return nil
+	}
+	shape, n := conformShape("", a, b)
+
+	na, nb := pa.Len(), pb.Len()
+	c.Alloc(n)
+	xs := make([]complex128, n)
+	var failed atomic.Bool
+	c.parallelFor(n, func(lo, hi int) {
+		for i := lo; i < hi; i++ {
+			ia, ib := i, i
+			if na == 1 {
+				ia = 0
+			}
+			if nb == 1 {
+				ib = 0
+			}
+			z, ok := kernel(pa.Cx(ia), pb.Cx(ib))
+			if !ok {
+				failed.Store(true)
+				return
+			}
+			xs[i] = z
+		}
+	})
+	if failed.Load() {
+		return nil
+	}
+	return packedResult(shape, xs)
+}
+
+// packedMonadic applies a kernel to every number of a numeric Mat,
+// without making a Val for each element.  It returns nil if it cannot.
+func packedMonadic(c *Context, b Val, kernel func(x complex128) (complex128, bool)) Val {
+	mat, ok := b.(*Mat)
+	if !ok {
+		return nil
+	}
+	p := packedOf(mat)
+	if p == nil {
+		return nil
+	}
+	c.Alloc(p.Len())
+	xs := make([]complex128, p.Len())
+	var failed atomic.Bool
+	c.parallelFor(len(xs), func(lo, hi int) {
+		for i := lo; i < hi; i++ {
+			z, ok := kernel(p.Cx(i))
+			if !ok {
+				failed.Store(true)
+				return
+			}
+			xs[i] = z
+		}
+	})
+	if failed.Load() {
+		return nil
+	}
+	return PackedMat(PackCxs(xs), mat.S)
+}
+
+// packedReduceOrScan reduces or scans a numeric Mat along the axis with a kernel,
+// folding from the left like MkReduceOrScanOp.
+// It returns nil if it cannot, and leaves errors and empty reductions to the general path.
+func packedReduceOrScan(c *Context, b Val, axis int, kernel FuncCxCxCx, toScan bool) Val {
+	mat, ok := b.(*Mat)
+	if !ok {
+		return nil
+	}
+	rank := len(mat.S)
+	if axis < 0 {
+		axis += rank
+	}
+	if rank == 0 || axis < 0 || axis >= rank || mat.S[axis] == 0 {
+		return nil
+	}
+	p := packedOf(mat)
+	if p == nil {
+		return nil
+	}
+
+	before, n, after := Product(mat.S[:axis]), mat.S[axis], Product(mat.S[axis+1:])
+	var shape []int
+	var xs []complex128
+	if toScan {
+		shape = mat.S
+	} else {
+		shape = append(append([]int{}, mat.S[:axis]...), mat.S[axis+1:]...)
+	}
+	xs = make([]complex128, c.AllocShape(shape))
+	// Each of the before*after folds is independent, so they can run in parallel.
+	c.parallelFor(before*after, func(lo, hi int) {
+		for ik := lo; ik < hi; ik++ {
+			i, k := ik/after, ik%after
+			at := func(j int) int { return (i*n+j)*after + k }
+			r := p.Cx(at(0))
+			if toScan {
+				xs[at(0)] = r
+			}
+			for j := 1; j < n; j++ {
+				r = kernel(r, p.Cx(at(j)))
+				if toScan {
+					xs[at(j)] = r
+				}
+			}
+			if !toScan {
+				xs[ik] = r
+			}
+		}
+	})
+	return packedResult(shape, xs)
+}
+
+// packedInnerProduct is the inner product of numeric Mats with kernels,
+// folding from the right like MkInnerProduct.
+// It returns nil if it cannot, and leaves errors to the general path.
+func packedInnerProduct(c *Context, a, b Val, kernel1, kernel2 FuncCxCxCx) Val {
+	mat1, ok1 := a.(*Mat)
+	mat2, ok2 := b.(*Mat)
+	if !ok1 || !ok2 {
+		return nil
+	}
+	rank1, rank2 := len(mat1.S), len(mat2.S)
+	if rank1 < 1 || rank2 < 1 || mat1.S[rank1-1] != mat2.S[0] || mat2.S[0] == 0 {
+		return nil
+	}
+	p1 := packedOf(mat1)
+	if p1 == nil {
+		return nil
+	}
+	p2 := packedOf(mat2)
+	if p2 == nil {
+		return nil
+	}
+
+	var shape []int
+	shape = append(shape, mat1.S[:rank1-1]...)
+	shape = append(shape, mat2.S[1:]...)
+	rows, inner, cols := Product(mat1.S[:rank1-1]), mat2.S[0], Product(mat2.S[1:])
+
+	c.Alloc(rows * cols)
+	xs := make([]complex128, rows*cols)
+	c.parallelFor(rows*cols, func(lo, hi int) {
+		for ik := lo; ik < hi; ik++ {
+			i, k := ik/cols, ik%cols
+			j := inner - 1
+			r := kernel2(p1.Cx(i*inner+j), p2.Cx(j*cols+k))
+			for j--; j >= 0; j-- {
+				r = kernel1(kernel2(p1.Cx(i*inner+j), p2.Cx(j*cols+k)), r)
+			}
+			xs[ik] = r
+		}
+	})
+	return packedResult(shape, xs)
+}
@@ -0,0 +1,98 @@
+package livy
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestPackCxsKinds(t *testing.T) {
+	negZero := complex(math.Copysign(0, -1), 0)
+	for _, tc := range []struct {
+		xs   []complex128
+		want string
+	}{
+		{[]complex128{}, "livy.Bits"},
+		{[]complex128{0, 1, 1, 0}, "livy.Bits"},
+		{[]complex128{0, 1, 2, -3}, "livy.Ints"},
+		{[]complex128{0, 1, 2.5}, "livy.Floats"},
+		{[]complex128{0, negZero}, "livy.Floats"},
+		{[]complex128{1e300, 2}, "livy.Floats"},
+		{[]complex128{0, 1, 2i}, "livy.Cxs"},
+	} {
+		p := PackCxs(append([]complex128{}, tc.xs...))
+		if got := fmt.Sprintf("%T", p); got != tc.want {
+			t.Errorf("PackCxs(%v) is %s, wanted %s", tc.xs, got, tc.want)
+		}
+		for i, x := range tc.xs {
+			if y := p.Cx(i); y != x || math.Signbit(real(y)) != math.Signbit(real(x)) {
+				t.Errorf("PackCxs(%v)[%d] is %v, wanted %v", tc.xs, i, y, x)
+			}
+		}
+	}
+}
+
+// Packed fast paths must give the same results as the general paths.
+func TestPackedMatchesGeneral(t *testing.T) {
+	c := NewContext()
+	general := map[string]DyadicFunc{
+		"+":  WrapMatMatDyadic(WrapCxDyadic(cxAdd)),
+		"*":  WrapMatMatDyadic(WrapCxDyadic(cxMul)),
+		"<":  WrapMatMatDyadic(WrapCompareDyadic(func(cmp int) bool { return cmp < 0 })),
+		"==": WrapMatMatDyadic(WrapEqualDyadic(false)),
+	}
+	args := []string{`7`, `iota 6`, `2 3 rho 0.5 1 -2 3 0 1`, `2 3 rho iota1 6`}
+	for name, fn := range general {
+		for _, sa := range args {
+			for _, sb := range args {
+				a, _ := c.EvalString(sa)
+				b, _ := c.EvalString(sb)
+				if _, ok := a.(*Mat); !ok {
+					if _, ok := b.(*Mat); !ok {
+						continue
+					}
+				}
+				want := catchString(func() Val { return fn(c, a, b, -1) })
+				got := catchString(func() Val { return StandardDyadics[name](c, a, b, -1) })
+				if got != want {
+					t.Errorf("(%s) %s (%s): got %s, wanted %s", sa, name, sb, got, want)
+				}
+			}
+		}
+	}
+}
+
+func catchString(fn func() Val) (s string) {
+	defer func() {
+		if r := recover(); r != nil {
+			s = "error"
+		}
+	}()
+	return fn().String()
+}
+
+func TestPackedLargeArrays(t *testing.T) {
+	c := NewContext()
+	z, err := c.EvalString(`iota 100000`)
+	if err != nil {
+		t.Fatalf("iota: %v", err)
+	}
+	if mat := z.(*Mat); mat.M != nil || mat.Len() != 100000 {
+		t.Errorf("iota 100000 was not left packed")
+	}
+	for src, want := range map[string]float64{
+		`+/ iota 100000`:                     4999950000,
+		`+/ 2 * iota 100000`:                 9999900000,
+		`(iota 1000) +.* iota 1000`:          332833500,
+		`+/ (1000 1000 rho 1) +.* iota 1000`: 499500000,
+	} {
+		z, err := c.EvalString(src)
+		if err != nil {
+			t.Errorf("%s: %v", src, err)
+			continue
+		}
+		if got := z.GetScalarFloat(); got != want {
+			t.Errorf("%s: got %v, wanted %v", src, got, want)
+		}
+	}
+}
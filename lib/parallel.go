@@ -0,0 +1,88 @@
+package livy
+
+import (
+	"runtime"
+	"sync"
+)
+
+// ParallelMin is the number of elements worth splitting among goroutines.
+// Smaller arrays are done in the calling goroutine.
+var ParallelMin = 10000
+
+// parallelDyadics are the standard scalar functions that may be called
+// from many goroutines at once, as operands of outer and inner products.
+var parallelDyadics = map[string]bool{
+	"+": true, "-": true, "*": true, "/": true, "div": true, "**": true,
+	"==": true, "!=": true, "<": true, ">": true, "<=": true, ">=": true,
+	"and": true, "or": true, "xor": true,
+	"mod": true, "remainder": true, "atan": true, "copysign": true, "dim": true, "hypot": true,
+	"j": true, "rect": true,
+}
+
+// parallelDyadic tells if the dyadic function of the name may run in parallel,
+// because it is a standard scalar function that has not been redefined.
+func (c *Context) parallelDyadic(name string) bool {
+	return c.isStandardDyadic(name) && parallelDyadics[name]
+}
+
+// workers is the number of goroutines for n elements:
+// Context.Workers (or one per CPU, if that is 0), but only if n is at least ParallelMin.
+func (c *Context) workers(n int) int {
+	if n < ParallelMin {
+		return 1
+	}
+	w := c.Workers
+	if w <= 0 {
+		w = runtime.NumCPU()
+	}
+	if w > n {
+		w = n
+	}
+	return w
+}
+
+// parallelFor calls fn on consecutive ranges of [0, n) that together cover it,
+// each in its own goroutine, if n is large enough.
+// Between every checkEvery elements, it checks for interrupts.
+// A panic in fn is raised again in the caller, after all the goroutines finish.
+// If more than one panics, the one for the earliest range is raised,
+// as if the ranges had been done in order.
+func (c *Context) parallelFor(n int, fn func(lo, hi int)) {
+	w := c.workers(n)
+	if w <= 1 {
+		c.checkedFor(0, n, fn)
+		return
+	}
+
+	panics := make([]interface{}, w)
+	var wg sync.WaitGroup
+	for k := 0; k < w; k++ {
+		lo, hi := k*n/w, (k+1)*n/w
+		wg.Add(1)
+		go func(k, lo, hi int) {
+			defer wg.Done()
+			defer func() {
+				panics[k] = recover()
+			}()
+			c.checkedFor(lo, hi, fn)
+		}(k, lo, hi)
+	}
+	wg.Wait()
+
+	for _, r := range panics {
+		if r != nil {
+			panic(r)
+		}
+	}
+}
+
+// allScalars tells if none of the values is a Mat,
+// so functions of them in parallel share no Mat.
+func allScalars(vec []Val) bool {
+	for _, v := range vec {
+		if _, ok := v.(*Mat); ok {
+			return false
+		}
+	}
+	return true
+}
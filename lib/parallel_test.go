@@ -0,0 +1,96 @@
+package livy
+
+import (
+	"fmt"
+	"testing"
+)
+
+// Results must not depend on how many goroutines compute them.
+func TestParallelMatchesSequential(t *testing.T) {
+	saved := ParallelMin
+	ParallelMin = 2
+	defer func() { ParallelMin = saved }()
+
+	for _, src := range []string{
+		`(iota 100) * 3`,
+		`(iota 100) < 50`,
+		`- 4 25 rho iota 100`,
+		`floor 0.5 * iota 100`,
+		`(exact iota 30) * 1r3`,
+		`+/ 10 10 rho iota 100`,
+		`+\ 10 10 rho iota 100`,
+		`(iota 10) ..* iota 12`,
+		`(exact iota 10) ..+ 1r2`,
+		`(iota 10) ..{ X - Y } iota 10`,
+		`(10 12 rho iota 120) +.* 12 7 rho iota 84`,
+		`(exact 4 5 rho iota 20) +.* 5 3 rho 1r2`,
+		`(1 2 3 4 , "a") + 1`,
+		`1 2 3 4 5 6 7 8 rho 1`,
+	} {
+		var got []string
+		for _, workers := range []int{1, 4} {
+			c := NewContext()
+			c.Workers = workers
+			z, err := c.EvalString(src)
+			if err != nil {
+				got = append(got, fmt.Sprintf("error %v", err))
+			} else {
+				got = append(got, z.String())
+			}
+		}
+		if got[0] != got[1] {
+			t.Errorf("%s: sequentially %s, but in parallel %s", src, got[0], got[1])
+		}
+	}
+}
+
+func TestParallelForPanicsInOrder(t *testing.T) {
+	saved := ParallelMin
+	ParallelMin = 2
+	defer func() { ParallelMin = saved }()
+
+	c := NewContext()
+	c.Workers = 4
+	defer func() {
+		if r := recover(); r != "at 30" {
+			t.Errorf("Got panic %v, wanted the first one, at 30", r)
+		}
+	}()
+	c.parallelFor(100, func(lo, hi int) {
+		for i := lo; i < hi; i++ {
+			if i == 30 || i == 80 {
+				panic(fmt.Sprintf("at %d", i))
+			}
+		}
+	})
+}
+
+// Only the Parallel wrappers may call a function from several goroutines.
+func TestWrapMatInOrder(t *testing.T) {
+	saved := ParallelMin
+	ParallelMin = 2
+	defer func() { ParallelMin = saved }()
+
+	c := NewContext()
+	c.Workers = 4
+	var seen []complex128 // Not safe for concurrent appends.
+	monadic := WrapMatMonadic(func(c *Context, b Val, axis int) Val {
+		seen = append(seen, b.GetScalarCx())
+		return b
+	})
+	dyadic := WrapMatMatDyadic(func(c *Context, a, b Val, axis int) Val {
+		seen = append(seen, b.GetScalarCx())
+		return b
+	})
+	x := evalIn(c, `iota 100`)
+	monadic(c, x, -1)
+	dyadic(c, Zero, x, -1)
+	if len(seen) != 200 {
+		t.Fatalf("Got %d calls, wanted 200", len(seen))
+	}
+	for i, y := range seen {
+		if y != complex(float64(i%100), 0) {
+			t.Fatalf("Call %d got %v, wanted %d", i, y, i%100)
+		}
+	}
+}
@@ -34,10 +34,29 @@ package livy
 
 import (
 	"strconv"
+	"strings"
 )
 
 type Parser struct {
 	Context *Context
+
+	// Number of operands of operators defined while parsing,
+	// before the def is evaluated into the Context.
+	operators map[string]int
+}
+
+// operatorArity tells how many function operands the named user-defined operator takes,
+// or 0 if it is not a user-defined operator.
+func (p *Parser) operatorArity(name string) int {
+	if n, ok := p.operators[name]; ok {
+		return n
+	}
+	if p.Context != nil {
+		if op, ok := p.Context.Operators[name]; ok {
+			return op.Arity
+		}
+	}
+	return 0
 }
 
 func (p *Parser) ParseSquare(lex *Lex, i int) ([]Expression, int) {
@@ -66,6 +85,13 @@ func (p *Parser) ParseSeq(lex *Lex, i int) (*Seq, int) {
 	var vec []Expression
 LOOP:
 	for i < len(tt) && tt[i].Type != EndToken {
+		// Skip empty statements, such as blank lines.
+		for tt[i].Type == SemiToken {
+			i++
+		}
+		if endsSeq(tt[i]) {
+			break LOOP
+		}
 		Log.Printf("ParseSeq: i=%d max=%d token=%s", i, len(tt), tt[i])
 		b, j := p.ParseExpr(lex, i)
 		Log.Printf("ParseSeq: i=%d b=%s", i, b)
@@ -75,10 +101,10 @@ LOOP:
 		switch tt[i].Type {
 		case KeywordToken:
 			switch tt[i].Str {
-			case "then", "else", "fi", "do", "done":
+			case "then", "else", "fi", "do", "done", "catch", "end":
 				break LOOP
 			default:
-				Log.Panicf("unexpected keyword: %q", tt[i].Str)
+				panicSourcef(SyntaxError, lex.Source, tt[i].Pos, "unexpected keyword: %q", tt[i].Str)
 			}
 		case EndToken, CloseCurlyToken:
 			break LOOP
@@ -86,13 +112,27 @@ LOOP:
 			i++
 			continue LOOP
 		default:
-			Log.Panicf("default: %d %s", i, tt[i])
+			panicSourcef(SyntaxError, lex.Source, tt[i].Pos, "unexpected %q", tt[i].Str)
 		}
 	}
 
 	return &Seq{vec}, i
 }
 
+// endsSeq tells if the token ends a sequence of statements.
+func endsSeq(t *Token) bool {
+	switch t.Type {
+	case EndToken, CloseCurlyToken:
+		return true
+	case KeywordToken:
+		switch t.Str {
+		case "then", "else", "fi", "do", "done", "catch", "end":
+			return true
+		}
+	}
+	return false
+}
+
 func (p *Parser) ParseWhile(lex *Lex, i int) (*While, int) {
 	tt := lex.Tokens
 	t := tt[i]
@@ -102,7 +142,7 @@ func (p *Parser) ParseWhile(lex *Lex, i int) (*While, int) {
 
 	t = tt[i]
 	if t.Str != "do" {
-		Log.Panicf("expected `do` but got %q", t.Str)
+		panicSourcef(SyntaxError, lex.Source, t.Pos, "expected `do` but got %q", t.Str)
 	}
 
 	i++
@@ -111,7 +151,7 @@ func (p *Parser) ParseWhile(lex *Lex, i int) (*While, int) {
 	i = j
 	t = tt[i]
 	if t.Str != "done" {
-		Log.Panicf("expected `done` but got %q", t.Str)
+		panicSourcef(SyntaxError, lex.Source, t.Pos, "expected `done` but got %q", t.Str)
 	}
 	z := &While{whileSeq, doSeq}
 	Log.Printf("ParseWhile returns %v", z)
@@ -127,7 +167,7 @@ func (p *Parser) ParseIf(lex *Lex, i int) (*Cond, int) {
 
 	t = tt[i]
 	if t.Str != "then" {
-		Log.Panicf("expected `then` but got %q", t.Str)
+		panicSourcef(SyntaxError, lex.Source, t.Pos, "expected `then` but got %q", t.Str)
 	}
 
 	i++
@@ -136,7 +176,7 @@ func (p *Parser) ParseIf(lex *Lex, i int) (*Cond, int) {
 	i = j
 	t = tt[i]
 	if t.Str != "else" {
-		Log.Panicf("expected `else` but got %q", t.Str)
+		panicSourcef(SyntaxError, lex.Source, t.Pos, "expected `else` but got %q", t.Str)
 	}
 	i++
 	t = tt[i]
@@ -144,18 +184,52 @@ func (p *Parser) ParseIf(lex *Lex, i int) (*Cond, int) {
 	i = j
 	t = tt[i]
 	if t.Str != "fi" {
-		Log.Panicf("expected `fI` but got %q", t.Str)
+		panicSourcef(SyntaxError, lex.Source, t.Pos, "expected `fi` but got %q", t.Str)
 	}
 	z := &Cond{ifSeq, thenSeq, elseSeq}
 	Log.Printf("ParseIf returns %v", z)
 	return z, i + 1
 }
 
+func (p *Parser) ParseTry(lex *Lex, i int) (*Try, int) {
+	tt := lex.Tokens
+
+	trySeq, j := p.ParseSeq(lex, i)
+	i = j
+	t := tt[i]
+	if t.Str != "catch" {
+		panicSourcef(SyntaxError, lex.Source, t.Pos, "expected `catch` but got %q", t.Str)
+	}
+
+	// The variable to bind is optional.
+	i++
+	t = tt[i]
+	var name string
+	if t.Type == VariableToken {
+		name = t.Str
+		i++
+	}
+
+	catchSeq, j := p.ParseSeq(lex, i)
+	i = j
+	t = tt[i]
+	if t.Str != "end" {
+		panicSourcef(SyntaxError, lex.Source, t.Pos, "expected `end` but got %q", t.Str)
+	}
+	z := &Try{trySeq, name, catchSeq}
+	Log.Printf("ParseTry returns %v", z)
+	return z, i + 1
+}
+
 func (p *Parser) ParseDef(lex *Lex, i int) (*Def, int) {
 	var lhs, axis, rhs string
 	var locals []string
 
+	var leftOperand, rightOperand string
+	var name string
+
 	tt := lex.Tokens
+	start := tt[i-1].Pos // Position of the `def` keyword.
 	t := tt[i]
 	// Expect operator.
 	if t.Type == VariableToken {
@@ -165,30 +239,69 @@ func (p *Parser) ParseDef(lex *Lex, i int) (*Def, int) {
 		t = tt[i]
 	}
 
-	if t.Type != OperatorToken {
-		Log.Panicf("expected operator after def, but got %v", t)
+	if t.Type == OpenToken {
+		// Defining an operator with operands, like `def (f power N) Y`.
+		i++
+		t = tt[i]
+		if t.Type != OperatorToken {
+			panicSourcef(SyntaxError, lex.Source, t.Pos, "expected function operand after def open-paren, but got %q", t.Str)
+		}
+		leftOperand = t.Str
+		i++
+		t = tt[i]
+		if t.Type != OperatorToken {
+			panicSourcef(SyntaxError, lex.Source, t.Pos, "expected operator name after def operand, but got %q", t.Str)
+		}
+		name = t.Str
+		i++
+		t = tt[i]
+		switch t.Type {
+		case OperatorToken:
+			rightOperand = t.Str // A function operand.
+			i++
+			t = tt[i]
+		case VariableToken:
+			rightOperand = t.Str // A value operand.
+			locals = append(locals, rightOperand)
+			i++
+			t = tt[i]
+		}
+		if t.Type != CloseToken {
+			panicSourcef(SyntaxError, lex.Source, t.Pos, "expected close-paren after def operator operands, but got %q", t.Str)
+		}
+		if p.operators == nil {
+			p.operators = make(map[string]int)
+		}
+		p.operators[name] = 1
+		if rightOperand != "" {
+			p.operators[name] = 2
+		}
+	} else {
+		if t.Type != OperatorToken {
+			panicSourcef(SyntaxError, lex.Source, t.Pos, "expected operator after def, but got %q", t.Str)
+		}
+		name = t.Str
 	}
-	name := t.Str
 	i++
 	t = tt[i]
 	if t.Type == OpenSquareToken {
 		i++
 		t = tt[i]
 		if t.Type != VariableToken {
-			Log.Panicf("expected AXIS variable after def operator open-bracket, but got %v", t)
+			panicSourcef(SyntaxError, lex.Source, t.Pos, "expected AXIS variable after def operator open-bracket, but got %q", t.Str)
 		}
 		axis = t.Str
 		locals = append(locals, axis)
 		i++
 		t = tt[i]
 		if t.Type != CloseSquareToken {
-			Log.Panicf("expected close-bracket def operator open-bracket axis, but got %v", t)
+			panicSourcef(SyntaxError, lex.Source, t.Pos, "expected close-bracket def operator open-bracket axis, but got %q", t.Str)
 		}
 		i++
 		t = tt[i]
 	}
 	if t.Type != VariableToken {
-		Log.Panicf("expected RHS variable after def, but got %v", t)
+		panicSourcef(SyntaxError, lex.Source, t.Pos, "expected RHS variable after def, but got %q", t.Str)
 	}
 	rhs = t.Str
 	locals = append(locals, rhs)
@@ -198,12 +311,15 @@ func (p *Parser) ParseDef(lex *Lex, i int) (*Def, int) {
 	for t.Type == SemiToken {
 		i++
 		t = tt[i]
-		// Allow extra semicolon before open curly.
+		// Allow extra semicolons or newlines before open curly.
 		if t.Type == OpenCurlyToken {
 			break
 		}
+		if t.Type == SemiToken {
+			continue
+		}
 		if t.Type != VariableToken {
-			Log.Panicf("expected local variable name after def semicolon, but got %v", t)
+			panicSourcef(SyntaxError, lex.Source, t.Pos, "expected local variable name after def semicolon, but got %q", t.Str)
 		}
 		locals = append(locals, t.Str)
 		i++
@@ -211,7 +327,7 @@ func (p *Parser) ParseDef(lex *Lex, i int) (*Def, int) {
 	}
 
 	if t.Type != OpenCurlyToken {
-		Log.Panicf("expected open-curly-brace after operator after def, but got %v", t)
+		panicSourcef(SyntaxError, lex.Source, t.Pos, "expected open-curly-brace after operator after def, but got %q", t.Str)
 	}
 	i++
 
@@ -220,10 +336,213 @@ func (p *Parser) ParseDef(lex *Lex, i int) (*Def, int) {
 	t = tt[i]
 
 	if t.Type != CloseCurlyToken {
-		Log.Panicf("expected close-curly-brace after operator after def, but got %v", t)
+		panicSourcef(SyntaxError, lex.Source, t.Pos, "expected close-curly-brace after operator after def, but got %q", t.Str)
 	}
+	source := lex.Source[start : t.Pos+1]
 	i++
-	return &Def{name, seq, lhs, axis, rhs, locals}, i
+	return &Def{name, seq, lhs, axis, rhs, locals, source, start, leftOperand, rightOperand}, i
+}
+
+func isFuncStart(t *Token) bool {
+	switch t.Type {
+	case EachToken, ScanToken, ReduceToken, InnerProductToken, OuterProductToken, OperatorToken, OpenCurlyToken:
+		return true
+	case DotToken:
+		return t.Str == ".."
+	}
+	return false
+}
+
+func isExprEnd(t *Token) bool {
+	switch t.Type {
+	case EndToken, CloseToken, CloseSquareToken, SemiToken, CloseCurlyToken, KeywordToken:
+		return true
+	}
+	return false
+}
+
+// ParseFunc parses anything that can stand in operator position:
+// an operator name (including the lexer's reduce, scan, each, and product forms),
+// a lambda in curly braces, or `..` before either for outer product,
+// followed by any number of `/` or `\` (after a lambda), `~`, or `.` and another function.
+func (p *Parser) ParseFunc(lex *Lex, i int) (FuncExpression, int) {
+	tt := lex.Tokens
+	fn, i := p.parseFuncPrimary(lex, i)
+	for {
+		t := tt[i]
+		_, named := fn.(*OpRef)
+		switch {
+		case t.Type == TildeToken:
+			fn = &Derived{"~", fn, nil}
+			i++
+		case t.Type == DotToken && t.Str == ".":
+			right, j := p.parseFuncPrimary(lex, i+1)
+			fn = &Derived{".", fn, right}
+			i = j
+		case t.Type == OperatorToken && p.operatorArity(t.Str) > 0:
+			var right FuncExpression
+			var rightVal Expression
+			j := i + 1
+			if p.operatorArity(t.Str) == 2 {
+				if isFuncStart(tt[j]) && p.operatorArity(tt[j].Str) == 0 {
+					right, j = p.parseFuncPrimary(lex, j)
+				} else {
+					rightVal, j = p.parseValuePrimary(lex, j)
+				}
+			}
+			fn = &OperatorCall{t.Str, fn, right, rightVal}
+			i = j
+		case t.Type == OperatorToken && (t.Str == "/" || t.Str == `\`) && !named:
+			// The lexer already combines names with / and \, so this follows a lambda.
+			fn = &Derived{t.Str, fn, nil}
+			i++
+		default:
+			return fn, i
+		}
+	}
+}
+
+func (p *Parser) parseFuncPrimary(lex *Lex, i int) (FuncExpression, int) {
+	tt := lex.Tokens
+	t := tt[i]
+	switch t.Type {
+	case EachToken, ScanToken, ReduceToken, InnerProductToken, OuterProductToken, OperatorToken:
+		return &OpRef{t}, i + 1
+	case OpenCurlyToken:
+		seq, j := p.ParseSeq(lex, i+1)
+		if tt[j].Type != CloseCurlyToken {
+			panicSourcef(SyntaxError, lex.Source, tt[j].Pos, "expected close-curly-brace after lambda, but got %q", tt[j].Str)
+		}
+		return &Lambda{seq, lex.Source[t.Pos : tt[j].Pos+1], t.Pos}, j + 1
+	case DotToken:
+		if t.Str == ".." {
+			left, j := p.parseFuncPrimary(lex, i+1)
+			return &Derived{"..", left, nil}, j
+		}
+	}
+	panicSourcef(SyntaxError, lex.Source, t.Pos, "expected a function, but got %q", t.Str)
+	panic(0)
+}
+
+// parseLiteral parses a number, complex number, or string token.
+func (p *Parser) parseLiteral(lex *Lex, t *Token) Expression {
+	switch t.Type {
+	case ComplexToken:
+		_m := MatchComplexSplit(t.Str)
+		if _m == nil {
+			panicSourcef(SyntaxError, lex.Source, t.Pos, "Error parsing ComplexToken %q", t.Str)
+		}
+		_r, _j, _i := _m[1], _m[2], _m[3]
+		var rl float64
+		if _r != "" {
+			_rl, err := strconv.ParseFloat(_r, 64)
+			if err != nil {
+				panicSourcef(SyntaxError, lex.Source, t.Pos, "Error parsing number %q", t.Str)
+			}
+			rl = _rl
+		}
+		cx, err := strconv.ParseFloat(_i, 64)
+		if err != nil {
+			panicSourcef(SyntaxError, lex.Source, t.Pos, "Error parsing number %q", t.Str)
+		}
+		if _j[0] == '-' {
+			cx = -cx
+		}
+		return &Number{complex(rl, cx)}
+	case NumberToken:
+		if strings.ContainsAny(t.Str, "xr") {
+			exact, ok := ParseExact(t.Str)
+			if !ok {
+				panicSourcef(SyntaxError, lex.Source, t.Pos, "Error parsing exact number %q", t.Str)
+			}
+			return &Literal{exact}
+		}
+		num, err := strconv.ParseFloat(t.Str, 64)
+		if err != nil {
+			panicSourcef(SyntaxError, lex.Source, t.Pos, "Error parsing number %q", t.Str)
+		}
+		return &Number{complex(num, 0)}
+	case StringToken:
+		s, err := strconv.Unquote(t.Str)
+		if err != nil {
+			panicSourcef(SyntaxError, lex.Source, t.Pos, "Error parsing string %s", t.Str)
+		}
+		if p.Context != nil && p.Context.StringExtension != nil {
+			return p.Context.StringExtension(s)
+		}
+		return &Literal{StringMat(s)}
+	}
+	panicSourcef(SyntaxError, lex.Source, t.Pos, "not a literal: %q", t.Str)
+	panic(0)
+}
+
+// tryParenFunc checks for nothing but a function inside parens,
+// returning the function and the index after the close-paren.
+func (p *Parser) tryParenFunc(lex *Lex, i int) (fn FuncExpression, j int, ok bool) {
+	tt := lex.Tokens
+	if !isFuncStart(tt[i+1]) {
+		return nil, i, false
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			fn, j, ok = nil, i, false // Not a function; parse it as an expression.
+		}
+	}()
+	fn, j = p.ParseFunc(lex, i+1)
+	if tt[j].Type != CloseToken {
+		return nil, i, false
+	}
+	return fn, j + 1, true
+}
+
+// parseValuePrimary parses the single value used as the right operand of an operator:
+// a number, a string, a variable, or an expression in parentheses.
+func (p *Parser) parseValuePrimary(lex *Lex, i int) (Expression, int) {
+	tt := lex.Tokens
+	t := tt[i]
+	switch t.Type {
+	case NumberToken, ComplexToken, StringToken:
+		return p.parseLiteral(lex, t), i + 1
+	case VariableToken:
+		return &Variable{t.Str, t.Pos}, i + 1
+	case OpenToken:
+		expr, j := p.ParseExpr(lex, i+1)
+		if tt[j].Type != CloseToken {
+			panicSourcef(SyntaxError, lex.Source, tt[j].Pos, "expected close-paren, but got %q", tt[j].Str)
+		}
+		return expr, j + 1
+	}
+	panicSourcef(SyntaxError, lex.Source, t.Pos, "expected an operand, but got %q", t.Str)
+	panic(0)
+}
+
+// parseApplication parses the optional axis and the RHS after a function,
+// and applies the function to the RHS and to the LHS values in vec, if any.
+func (p *Parser) parseApplication(lex *Lex, t *Token, fn FuncExpression, i int, vec []Expression) (Expression, int) {
+	tt := lex.Tokens
+	axis := Expression(nil)
+	if tt[i].Type == OpenSquareToken {
+		Log.Printf("Axis1")
+		var j int
+		axis, j = p.ParseExpr(lex, i+1)
+		Log.Printf("Axis2 %d %s", j, axis)
+		if tt[j].Type != CloseSquareToken {
+			panicSourcef(SyntaxError, lex.Source, tt[j].Pos, "Expected ']' but got %q after subscript", tt[j].Str)
+		}
+		i = j + 1
+	}
+
+	Log.Printf("===== PE [%d]", i)
+	b, j := p.ParseExpr(lex, i)
+	Log.Printf("===== PE [%d] --> %v %d", i, b, j)
+	switch len(vec) {
+	case 0:
+		return &Monad{t, fn.String(), b, axis, fn}, j
+	case 1:
+		return &Dyad{t, vec[0], fn.String(), b, axis, fn}, j
+	default:
+		return &Dyad{t, &List{vec}, fn.String(), b, axis, fn}, j
+	}
 }
 
 func (p *Parser) ParseExpr(lex *Lex, i int) (z Expression, zi int) {
@@ -234,17 +553,19 @@ LOOP:
 		t := tt[i]
 		Log.Printf("........ [%d] %q %v", i, t.Str, t)
 
-		/*
-			// Special syntax check for named curly fn.
-			if len(tt) > i + 3 &&
-			tt[i].Type == OperatorToken &&
+		// Special syntax check for naming a function, like `f = { X + Y }`.
+		if len(vec) == 0 &&
+			t.Type == OperatorToken &&
+			tt[i+1].Type == OperatorToken &&
 			tt[i+1].Str == "=" &&
-			tt[i+2].Type == OpenCurlyToken {
-			  expr, j := ParseCurly(p, i+3)
-			  i = j
-			  continue LOOP
+			isFuncStart(tt[i+2]) {
+			fn, j := p.ParseFunc(lex, i+2)
+			if !isExprEnd(tt[j]) {
+				panicSourcef(SyntaxError, lex.Source, tt[j].Pos, "expected only a function after `%s =`, but got %q", t.Str, tt[j].Str)
 			}
-		*/
+			source := strings.TrimSpace(lex.Source[t.Pos:tt[j].Pos])
+			return &FuncAssign{t.Str, fn, source}, j
+		}
 
 		// Big switch for normal cases.
 		switch t.Type {
@@ -268,85 +589,40 @@ LOOP:
 				while, j := p.ParseWhile(lex, i+1)
 				vec = append(vec, while)
 				i = j
-			case "then", "else", "fi", "do", "done":
+			case "try":
+				try, j := p.ParseTry(lex, i+1)
+				vec = append(vec, try)
+				i = j
+			case "then", "else", "fi", "do", "done", "catch", "end":
 				break LOOP
 			default:
-				Log.Panicf("initial keyword not implemented: %q", t.Str)
+				panicSourcef(SyntaxError, lex.Source, t.Pos, "initial keyword not implemented: %q", t.Str)
 			}
 		case EndToken, CloseToken, CloseSquareToken, SemiToken, CloseCurlyToken:
 			break LOOP
 		case OpenSquareToken:
-			Log.Panicf("Unexpected `[` at position %d: %s", t.Pos, lex.Source)
-		case OpenCurlyToken:
-			Log.Panicf("Unexpected `{` at position %d: %s", t.Pos, lex.Source)
-		case EachToken, ScanToken, ReduceToken, InnerProductToken, OuterProductToken, OperatorToken:
-			axis := Expression(nil)
-			var j int
-			if tt[i+1].Type == OpenSquareToken {
-				Log.Printf("Axis1")
-				axis, j = p.ParseExpr(lex, i+2)
-				Log.Printf("Axis2 %d %s", j, axis)
-				if tt[j].Type != CloseSquareToken {
-					Log.Panicf("Expected ']' but got %q after subscript", tt[i].Str)
-				}
-				i = j // Don't add 1 here; ParseExpr just below gets i+1.
-			}
-
-			Log.Printf("===== PE [%d]", i+1)
-			b, j := p.ParseExpr(lex, i+1)
-			Log.Printf("===== PE [%d] --> %v %d", i+1, b, j)
-			switch len(vec) {
-			case 0:
-				return &Monad{t, t.Str, b, axis}, j
-			case 1:
-				return &Dyad{t, vec[0], t.Str, b, axis}, j
-			default:
-				return &Dyad{t, &List{vec}, t.Str, b, axis}, j
-			}
-		case ComplexToken:
-			{
-				_m := MatchComplexSplit(t.Str)
-				if _m == nil {
-					Log.Panicf("Error parsing ComplexToken %q at position %d: %s", t.Str, t.Pos, lex.Source)
-				}
-				_r, _j, _i := _m[1], _m[2], _m[3]
-				var rl float64
-				if _r != "" {
-					_rl, err := strconv.ParseFloat(_r, 64)
-					if err != nil {
-						Log.Panicf("Error parsing number %q at position %d: %s", t.Str, t.Pos, lex.Source)
-					}
-					rl = _rl
-				}
-				cx, err := strconv.ParseFloat(_i, 64)
-				if err != nil {
-					Log.Panicf("Error parsing number %q at position %d: %s", t.Str, t.Pos, lex.Source)
-				}
-				if _j[0] == '-' {
-					cx = -cx
-				}
-				vec = append(vec, &Number{complex(rl, cx)})
-				i++
-			}
-		case NumberToken:
-			num, err := strconv.ParseFloat(t.Str, 64)
-			if err != nil {
-				Log.Panicf("Error parsing number %q at position %d: %s", t.Str, t.Pos, lex.Source)
-			}
-			vec = append(vec, &Number{complex(num, 0)})
+			panicSourcef(SyntaxError, lex.Source, t.Pos, "Unexpected `[`")
+		case TildeToken:
+			panicSourcef(SyntaxError, lex.Source, t.Pos, "Unexpected `~`")
+		case EachToken, ScanToken, ReduceToken, InnerProductToken, OuterProductToken, OperatorToken, OpenCurlyToken, DotToken:
+			fn, j := p.ParseFunc(lex, i)
+			return p.parseApplication(lex, t, fn, j, vec)
+		case ComplexToken, NumberToken:
+			vec = append(vec, p.parseLiteral(lex, t))
 			i++
 		case StringToken:
-			s, err := strconv.Unquote(t.Str)
-			if err != nil {
-				Log.Panicf("Error parsing string %s at position %d: %s", t.Str, t.Pos, lex.Source)
-			}
-			if p.Context.StringExtension == nil {
-				Log.Panicf("No StringExtension in this interpreter")
-			}
-			vec = append(vec, p.Context.StringExtension(s))
+			str := p.parseLiteral(lex, t)
 			i++
+			// Allow brackets after strings e.g. "hello"[1]
+			if tt[i].Type == OpenSquareToken {
+				subs, j := p.ParseSquare(lex, i)
+				vec = append(vec, &Subscript{str, subs})
+				i = j
+			} else {
+				vec = append(vec, str)
+			}
 		case VariableToken:
-			variable := &Variable{t.Str}
+			variable := &Variable{t.Str, t.Pos}
 			i++
 			if tt[i].Type == OpenSquareToken {
 				Log.Printf("B1")
@@ -359,7 +635,14 @@ LOOP:
 				vec = append(vec, variable)
 			}
 		case OpenToken:
+			if fn, j, ok := p.tryParenFunc(lex, i); ok {
+				// A function in parens, like `(double power 3) 5`.
+				return p.parseApplication(lex, t, fn, j, vec)
+			}
 			expr, j := p.ParseExpr(lex, i+1)
+			if tt[j].Type != CloseToken {
+				panicSourcef(SyntaxError, lex.Source, tt[j].Pos, "expected close-paren, but got %q", tt[j].Str)
+			}
 			i = j + 1
 			// Allow brackets after parens e.g. (iota1 10)[2 4 6]
 			if tt[i].Type == OpenSquareToken {
@@ -373,12 +656,12 @@ LOOP:
 				vec = append(vec, expr)
 			}
 		default:
-			Log.Panicf("bad default: %d", t.Type)
+			panicSourcef(SyntaxError, lex.Source, t.Pos, "unexpected %q", t.Str)
 		}
 	}
 
 	if len(vec) == 0 {
-		Log.Panicf("Error parsing expression; perhaps an operator followed by no expression: %q %q", tt[i-1].Str, tt[i].Str)
+		panicSourcef(SyntaxError, lex.Source, tt[i].Pos, "Error parsing expression; perhaps an operator followed by no expression: %q %q", tt[i-1].Str, tt[i].Str)
 	}
 	if len(vec) > 1 {
 		return &List{vec}, i
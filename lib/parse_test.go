@@ -17,10 +17,11 @@ var parserTests = []srcWantPair{
 }
 
 func Standard() *Context {
+	r := NewRegistry()
 	c := &Context{
 		Globals:  make(map[string]Val),
-		Monadics: StandardMonadics,
-		Dyadics:  StandardDyadics,
+		Monadics: r.Monadics,
+		Dyadics:  r.Dyadics,
 	}
 	c.Globals["Pi"] = &Num{math.Pi}
 	c.Globals["Tau"] = &Num{2.0 * math.Pi}
@@ -199,13 +200,13 @@ var evalTests = []srcWantPair{
 		`[5 10 ]{10 11 12 13 14 15 16 17 18 19 0 0 0 0 0 0 0 0 0 0 20 21 22 23 24 25 26 27 28 29 0 0 0 0 0 0 0 0 0 0 30 31 32 33 34 35 36 37 38 39 } `},
 	{`1 0 1 0 1 \[1] 10 + 10 10 rho iota 100`,
 		`[10 5 ]{10 0 11 0 12 20 0 21 0 22 30 0 31 0 32 40 0 41 0 42 50 0 51 0 52 60 0 61 0 62 70 0 71 0 72 80 0 81 0 82 90 0 91 0 92 100 0 101 0 102 } `},
-	{`1 0 1 0 1 /[0] 10 + 10 10 rho iota 100`,
+	{`1 0 1 0 1 compress[0] 10 + 10 10 rho iota 100`,
 		`[3 10 ]{10 11 12 13 14 15 16 17 18 19 30 31 32 33 34 35 36 37 38 39 50 51 52 53 54 55 56 57 58 59 } `},
-	{`1 0 1 0 1 /[1] 10 + 10 10 rho iota 100`,
+	{`1 0 1 0 1 compress[1] 10 + 10 10 rho iota 100`,
 		`[10 3 ]{10 12 14 20 22 24 30 32 34 40 42 44 50 52 54 60 62 64 70 72 74 80 82 84 90 92 94 100 102 104 } `},
 
 	// primes
-	{`N=100; ( 2 == +/ 0 == (iota1 N) ..mod iota1 N ) / iota1 N`,
+	{`N=100; ( 2 == +/ 0 == (iota1 N) ..mod iota1 N ) compress iota1 N`,
 		`[25 ]{2 3 5 7 11 13 17 19 23 29 31 37 41 43 47 53 59 61 67 71 73 79 83 89 97 } `},
 
 	// resize to scalar
@@ -214,7 +215,7 @@ var evalTests = []srcWantPair{
 	// def
 	{`def twice _x { _x * 2 } ; twice 4 5 6`, `[3 ]{8 10 12 } `},
 
-	{`def primes N { ( 2 == +/ 0 == (iota1 N) ..mod iota1 N ) / iota1 N } ; primes 20`, `[8 ]{2 3 5 7 11 13 17 19 } `},
+	{`def primes N { ( 2 == +/ 0 == (iota1 N) ..mod iota1 N ) compress iota1 N } ; primes 20`, `[8 ]{2 3 5 7 11 13 17 19 } `},
 
 	{` def A dot B; C; D; E { C = D = E = F = 999 ;  A +.* B } ; 10 20 30 dot 1 2 3 `, `140 `},
 	{` def A dot B; C; D; E; { C = D = E = F = 999 ;  A +.* B } ; 10 20 30 dot 1 2 3 `, `140 `},
@@ -265,7 +266,7 @@ var evalTests = []srcWantPair{
 	{` (6 7 rho iota 42) member (7 7 rho 3 * iota 10) `,
 		`[6 7 ]{1 0 0 1 0 0 1 0 0 1 0 0 1 0 0 1 0 0 1 0 0 1 0 0 1 0 0 1 0 0 0 0 0 0 0 0 0 0 0 0 0 0 } `},
 
-	{` A = , 7 7 rho (3 * iota 30) mod 19 ; up A `, `[49 ]{0 19 30 13 43 37 7 26 31 20 1 14 44 38 27 8 32 2 21 45 15 39 28 9 33 3 22 16 46 29 40 10 4 23 34 47 17 11 41 5 24 35 18 48 12 42 36 25 6 } `},
+	{` A = , 7 7 rho (3 * iota 30) mod 19 ; up A `, `[49 ]{0 19 30 13 43 7 26 37 1 20 31 14 44 8 27 38 2 21 32 15 45 9 28 39 3 22 33 16 46 10 29 40 4 23 34 17 47 11 41 5 24 35 18 48 12 42 6 25 36 } `},
 	{` A = , 7 7 rho (3 * iota 30) mod 19 ; A [ up A ]`,
 		`[49 ]{0 0 0 1 1 2 2 2 3 3 3 4 4 5 5 5 6 6 6 7 7 8 8 8 9 9 9 10 10 11 11 11 12 12 12 13 13 14 14 15 15 15 16 16 17 17 18 18 18 } `},
 	{` A = , 7 7 rho (3 * iota 30) mod 19 ; A [ down A ]`,
@@ -287,22 +288,265 @@ var evalTests = []srcWantPair{
 	{`(3 4 p i1 100 ) laminate[1] (3 4 p 100 + i1 100)`, `[3 2 4 ]{1 2 3 4 101 102 103 104 5 6 7 8 105 106 107 108 9 10 11 12 109 110 111 112 } `},
 	{`(3 4 p i1 100 ) laminate    (3 4 p 100 + i1 100)`, `[3 4 2 ]{1 101 2 102 3 103 4 104 5 105 6 106 7 107 8 108 9 109 10 110 11 111 12 112 } `},
 	{`,  -11 -11 take (i 10) rot 10 10 p i 10`, `[121 ]{0 0 0 0 0 0 0 0 0 0 0 0 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 0 2 3 4 5 6 7 8 9 0 1 0 3 4 5 6 7 8 9 0 1 2 0 4 5 6 7 8 9 0 1 2 3 0 5 6 7 8 9 0 1 2 3 4 0 6 7 8 9 0 1 2 3 4 5 0 7 8 9 0 1 2 3 4 5 6 0 8 9 0 1 2 3 4 5 6 7 0 9 0 1 2 3 4 5 6 7 8 } `},
+
+	// chars
+	{`rho "hello"`, `[1 ]{5 } `},
+	{`2 3 rho "abc"`, `[2 3 ]{'a' 'b' 'c' 'a' 'b' 'c' } `},
+	{`-4 take rot "abc"`, `[4 ]{' ' 'c' 'b' 'a' } `},
+	{`"ab" , "cd"`, `[4 ]{'a' 'b' 'c' 'd' } `},
+	{`1 0 1 compress "xyz"`, `[2 ]{'x' 'z' } `},
+	{`A = "hello" ; A[4 0]`, `[2 ]{'o' 'h' } `},
+	{`"hello"[4 0]`, `[2 ]{'o' 'h' } `},
+	{`"hello"[1] , "abc"[2]`, `[2 ]{'e' 'c' } `},
+	{`"abc" == "abd"`, `[3 ]{1 1 0 } `},
+	{`"a1" == 97 1`, `[2 ]{0 0 } `},
+	{`"hello" member "lo"`, `[5 ]{0 0 1 1 1 } `},
+	{`s2b "hi"`, `[2 ]{104 105 } `},
+	{`b2s 104 105`, `[2 ]{'h' 'i' } `},
+
+	// lambdas
+	{`{ Y * 2 } 4 5 6`, `[3 ]{8 10 12 } `},
+	{`10 { X - Y } 3`, `7 `},
+	{`{ X + Y }/ iota1 4`, `10 `},
+	{`{ X * Y }\ 1 2 3`, `[3 ]{1 2 6 } `},
+	{`{ X * Y }\ iota 0`, `[0 ]{} `},
+	{`{ iota Y }~ 2 3`, `[2 ]{[2 ]{0 1 } [3 ]{0 1 2 } } `},
+	{`1 2 { X + 10 * Y }~ 3 4`, `[2 ]{31 42 } `},
+	{`(iota 3) ..{ X + 10 * Y } iota 2`, `[3 2 ]{0 10 1 11 2 12 } `},
+	{`(2 2 rho 1 2 3 4) +.{ X * Y } 10 100`, `[2 ]{210 430 } `},
+	{`hyp = { sqrt (X*X) + Y*Y } ; 3 hyp 4`, `5 `},
+	{`plus = { X + Y } ; plus/ 1 2 3 4`, `10 `},
+	{`times = { X * Y } ; (iota1 3) ..times iota1 3`, `[3 3 ]{1 2 3 2 4 6 3 6 9 } `},
+	{`sum = +/ ; sum iota1 10`, `55 `},
+
+	// format
+	{`format 42`, `[2 ]{'4' '2' } `},
+	{`format -1.5`, `[4 ]{'-' '1' '.' '5' } `},
+	{`and/ "1  2  3" == format 1 2 3`, `1 `},
+	{`rho format 2 3 rho 1 10 100 1000 -5 2.5`, `[2 ]{2 13 } `},
+	{`and/ "3+j4" == format 3+j4`, `1 `},
+	{`rho format 2 2 2 rho iota 8`, `[2 ]{5 4 } `},
+	{`and/ "  3.14 -1.50" == 6 2 format 3.14159 -1.5`, `1 `},
+	{`rho 6 1 4 0 format 2 2 rho 1.25 2 3.5 400`, `[2 ]{2 10 } `},
+	{`and/ "**" == 2 0 format 1234`, `1 `},
+	{`and/ " 1.00 22.00" == 0 2 format 1 22`, `1 `},
+	{`and/ "3.00-j4.00" == 10 2 format 3-j4`, `1 `},
+
+	// comparison tolerance
+	{`(0.1 + 0.2) == 0.3`, `0 `},
+	{`$CT = 1e-14 ; (0.1 + 0.2) == 0.3`, `1 `},
+	{`$CT = 1e-14 ; (0.1 + 0.2) != 0.3`, `0 `},
+	{`$CT = 1e-14 ; (0.1 + 0.2) < 0.3`, `0 `},
+	{`$CT = 1e-14 ; (0.1 + 0.2) <= 0.3`, `1 `},
+	{`$CT = 1e-14 ; 0.3 < 0.30001`, `1 `},
+	{`$CT = 1e-14 ; 0.3 >= 0.1 + 0.2`, `1 `},
+	{`$CT = 1e-14 ; iota 0.1 * 3 * 10`, `[3 ]{0 1 2 } `},
+	{`$CT = 1e-14 ; floor 2.9999999999999996 2.5 -0.5`, `[3 ]{3 2 -1 } `},
+	{`$CT = 1e-14 ; ceil 3.0000000000000004 2.5 -0.5`, `[3 ]{3 3 -0 } `},
+	{`floor 2.9999999999999996`, `2 `},
+	{`$CT = 1e-14 ; (0.1 * 1 2 3) member 0.2 (0.1 + 0.2)`, `[3 ]{0 1 1 } `},
+	{`$CT = 1e-14 ; (iota 50) iota 3.0000000000000004 2.5`, `[2 ]{3 50 } `},
+	{`$CT = 1e-14 ; (0.1 * iota 50) iota 0.1 + 0.2`, `3 `},
+	{`$CT = 1e-14 ; $CT = 0 ; (0.1 + 0.2) == 0.3`, `0 `},
+	{`$CT = 1e-10 ; $CT`, `1e-10 `},
+
+	// system variables and functions
+	{`$PP = 3 ; format 3.14159`, `[4 ]{'3' '.' '1' '4' } `},
+	{`$PP = 3 ; $PP = 0 ; format 3.14159`, `[7 ]{'3' '.' '1' '4' '1' '5' '9' } `},
+	{`rho $TS`, `[1 ]{7 } `},
+	{`(1 take $TS) > 2000`, `[1 ]{1 } `},
+	{`$WSID = "abc" ; $WSID`, `[3 ]{'a' 'b' 'c' } `},
+	{`$RL = 7 ; $RL`, `7 `},
+	{`$nl 2`, `[5 4 ]{'O' 'n' 'e' ' ' 'P' 'i' ' ' ' ' 'T' 'a' 'u' ' ' 'T' 'w' 'o' ' ' 'Z' 'e' 'r' 'o' } `},
+	{`Zr = 1 ; Zq = 22 ; rho $nl 2`, `[2 ]{7 4 } `},
+	{`def ff X { X } ; def X gg Y { Y } ; $nl 3`, `[2 2 ]{'f' 'f' 'g' 'g' } `},
+	{`Zq = 1 ; $ex "Zq Zz"`, `[2 ]{1 0 } `},
+	{`Zq = 1 ; $ex "Zq" ; rho $nl 2`, `[2 ]{5 4 } `},
+
+	// conformability
+	{`(2 3 rho 1 2 3 4 5 6) + 2 3 rho 10`, `[2 3 ]{11 12 13 14 15 16 } `},
+	{`(1 1 rho 5) + 1 2 3`, `[3 ]{6 7 8 } `},
+	{`(,5) * 2 2 rho 1 2 3 4`, `[2 2 ]{5 10 15 20 } `},
+	{`(1 1 rho 5) + ,3`, `[1 1 ]{8 } `},
+	{`(,3) + 1 1 rho 5`, `[1 1 ]{8 } `},
+	{`(,5) {X , Y}~ 1 2`, `[2 ]{[2 ]{5 1 } [2 ]{5 2 } } `},
+	{`(2 2 rho 1 2 3 4) {X * Y}~ 2 2 rho 10`, `[2 2 ]{10 20 30 40 } `},
+	{`try (2 3 rho 1) + 3 2 rho 1 catch E 6 take E end`, `[6 ]{'L' 'E' 'N' 'G' 'T' 'H' } `},
+	{`try (2 3 rho 1) {X + Y}~ 3 2 rho 1 catch E 6 take E end`, `[6 ]{'L' 'E' 'N' 'G' 'T' 'H' } `},
+	{`try (2 3 rho 1) {X + Y}~ 6 rho 1 catch E 4 take E end`, `[4 ]{'R' 'A' 'N' 'K' } `},
+
+	// outer product of any rank
+	{`3 ..+ 10 20`, `[2 ]{13 23 } `},
+	{`(1 2) ..+ 10`, `[2 ]{11 12 } `},
+	{`3 ..* 4`, `12 `},
+	{`(2 2 rho 1 2 3 4) ..* 1 10`, `[2 2 2 ]{1 10 2 20 3 30 4 40 } `},
+	{`rho (2 3 rho 1) ..+ 4 5 rho 1`, `[4 ]{2 3 4 5 } `},
+	{`rho (iota 0) ..+ 1 2`, `[2 ]{0 2 } `},
+	{`"ab" ..== "abc"`, `[2 3 ]{1 0 0 0 1 0 } `},
+	{`def X pl Y { X + 10 * Y } ; (1 2) ..pl 2 2 rho 1 2 3 4`, `[2 2 2 ]{11 21 31 41 12 22 32 42 } `},
+	{`def X pl Y { X + 10 * Y } ; 1 2 .. pl~ 3 4`, `[2 2 ]{31 41 32 42 } `},
+
+	// packed arrays
+	{`+/ iota 1000`, `499500 `},
+	{`-/ iota1 4`, `-8 `},
+	{`+\ 2 3 rho iota 6`, `[2 3 ]{0 1 3 3 7 12 } `},
+	{`+/[0] 2 3 rho iota 6`, `[3 ]{3 5 7 } `},
+	{`(2 3 rho iota 6) +.* 3 2 rho iota 6`, `[2 2 ]{10 13 28 40 } `},
+	{`(iota 4) * 0.5`, `[4 ]{0 0.5 1 1.5 } `},
+	{`(iota 4) < 2 0 2 9`, `[4 ]{1 0 0 1 } `},
+	{`(iota 3) , "a"`, `[4 ]{0 1 2 'a' } `},
+	{`def X + Y { X - Y } ; +/ 1 2 3`, `-4 `},
+
+	// exact numbers
+	{`30x`, `30x `},
+	{`*/ exact iota1 25`, `15511210043330985984000000x `},
+	{`1r3 + 1r6`, `1r2 `},
+	{`1r3 * 3`, `1x `},
+	{`1r3 + 0.5`, `0.8333333333333333 `},
+	{`(2x ** 62) + 2x ** 62`, `9223372036854775808x `},
+	{`(2x ** 64) - 1`, `18446744073709551615x `},
+	{`2x ** -2`, `1r4 `},
+	{`1x / 3 6`, `[2 ]{1r3 1r6 } `},
+	{`-7x mod 3`, `-1x `},
+	{`(floor -7r2) , ceil -7r2`, `[2 ]{-4x -3x } `},
+	{`(2x ** 60) == 1 + 2x ** 60`, `0 `},
+	{`(2 ** 60) == 1 + 2 ** 60`, `1 `},
+	{`1r3 < 1r2`, `1 `},
+	{`up 3x 1r2 2 0.1`, `[4 ]{3 1 2 0 } `},
+	{`inexact 1r4`, `0.25 `},
+	{`exact 0.5`, `1r2 `},
+	{`(iota 5x) , 3x rho 1`, `[8 ]{0 1 2 3 4 1 1 1 } `},
+
+	// random numbers
+	{`$RL = 5 ; A = roll 20 rho 6 ; $RL = 5 ; and/ A == roll 20 rho 6`, `1 `},
+	{`$RL = 1 ; A = uniform 3 ; $RL = 1 ; and/ A == uniform 3`, `1 `},
+	{`A = roll 100 rho 6 ; (and/ A >= 0) , (and/ A < 6) , and/ A == floor A`, `[3 ]{1 1 1 } `},
+	{`A = roll 0 0 ; (and/ A >= 0) , and/ A < 1`, `[2 ]{1 1 } `},
+	{`+/ 10 deal 10`, `45 `},
+	{`rho 3 deal 50`, `[1 ]{3 } `},
+	{`rho 0 deal 0`, `[1 ]{0 } `},
+	{`A = , uniform 4 5 ; (rho A) , (and/ A >= 0) , and/ A < 1`, `[3 ]{20 1 1 } `},
+	{`rho normal 2 3`, `[2 ]{2 3 } `},
+
+	// index-of, member, and grade
+	{`10 20 30 20 iota 20 40 10`, `[3 ]{1 4 0 } `},
+	{`10 20 30 iota 30`, `2 `},
+	{`"hello" iota "lo"`, `[2 ]{2 4 } `},
+	{`(1+j2) 3 (1-j2) iota 1-j2`, `2 `},
+	{`(1+j2) 3 member 1-j2 1+j2`, `[2 ]{1 0 } `},
+	{`(iota 100) iota 99 100 0`, `[3 ]{99 100 0 } `},
+	{`(100 rho 3 1 4 1 5) iota 4 5 9`, `[3 ]{2 4 100 } `},
+	{`((iota 40) , "x") iota "x" , 39`, `[2 ]{40 39 } `},
+	{`(iota1 50) member 2 3 rho 10 99 7 20 -1 50`, `[50 ]{0 0 0 0 0 0 1 0 0 1 0 0 0 0 0 0 0 0 0 1 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 1 } `},
+	{`5 member 1 2 3`, `0 `},
+	{`up 3 1 2 1 3`, `[5 ]{1 3 2 0 4 } `},
+	{`down 3 1 2 1 3`, `[5 ]{0 4 2 1 3 } `},
+	{`up 4 2 rho 2 1  1 9  2 0  1 9`, `[4 ]{1 3 2 0 } `},
+	{`down 4 2 rho 2 1  1 9  2 0  1 9`, `[4 ]{0 2 1 3 } `},
+	{`up "banana"`, `[6 ]{1 3 5 0 2 4 } `},
+	{`up 3 2 3 rho iota 18`, `[3 ]{0 1 2 } `},
+
+	// encode and decode
+	{`24 60 60 encode 3723`, `[3 ]{1 2 3 } `},
+	{`24 60 60 decode 1 2 3`, `3723 `},
+	{`0 60 60 encode 100000`, `[3 ]{27 46 40 } `},
+	{`2 2 2 encode 5 6 7`, `[3 3 ]{1 1 1 0 1 1 1 0 1 } `},
+	{`2 2 2 encode -1`, `[3 ]{1 1 1 } `},
+	{`10 encode 123`, `3 `},
+	{`0 10 encode 123`, `[2 ]{12 3 } `},
+	{`2 decode 1 0 1`, `5 `},
+	{`24 60 60 decode 3 2 rho 1 2 3 4 5 6`, `[2 ]{3785 7446 } `},
+	{`(3 2 rho 10 2) encode 5 6`, `[3 2 2 ]{0 0 1 1 0 0 0 1 5 6 1 0 } `},
+	{`(2 3 rho 10 10 10 2 2 2) decode 1 0 1`, `[2 ]{101 5 } `},
+	{`24 60 60 decode 24 60 60 encode 86399`, `86399 `},
+
+	// eval
+	{`eval "1 + 2"`, `3 `},
+	{`eval "A = 10" ; A * 2`, `20 `},
+	{`def twice Y ; K { K = 2 ; eval "K * Y" } ; twice 21`, `42 `},
+	{`eval box "iota 3"`, `[3 ]{0 1 2 } `},
+	{`S = "+" ; eval S , "/ 4 5 6"`, `15 `},
+	{`try eval "1 +" catch E 6 take E end`, `[6 ]{'S' 'Y' 'N' 'T' 'A' 'X' } `},
+	{`try eval "Nope" catch E 5 take E end`, `[5 ]{'V' 'A' 'L' 'U' 'E' } `},
+
+	// multi-line statements and comments
+	{"def fact N {\n  if N < 2 then\n    1\n  else\n    N * fact N - 1\n  fi\n}\nfact 5", `120 `},
+	{"def X mul Y ; Z   # Comment after header.\n{\n  # A whole line of comment.\n  Z = X * Y\n\n  Z\n}\n3 mul 8", `24 `},
+	{"I = 0 ; S = 0\nwhile I < 5\ndo\n  S = S + I\n  I = I + 1\ndone\nS", `10 `},
+	{"1 + 2 # Three.", `3 `},
+
+	// lexical scope
+	{`def peek Y { Q } ; def poke Y ; Q { Q = 5 ; peek Y } ; Q = 1 ; poke 0`, `1 `},
+	{`def setq Y ; Q { Q = Y } ; Q = 1 ; setq 99 ; Q`, `1 `},
+	{`def setz Y { Z = Y ; Z * 2 } ; Z = 1 ; (setz 99) , Z`, `[2 ]{198 1 } `},
+	{`def fib N ; A ; B { if N < 2 then N else A = fib N - 1 ; B = fib N - 2 ; A + B fi } ; fib 10`, `55 `},
+	{`def X addeach Y { { X + Y }~ Y } ; 100 addeach 1 2 3`, `[3 ]{101 102 103 } `},
+	{`def scaled Y ; K { K = 10 ; { K * Y } Y } ; K = 1 ; scaled 4`, `40 `},
+	{`def (f twice) Y { f f Y } ; def f Y { Y + 1000 } ; double twice 3`, `12 `},
+
+	// try and catch
+	{`try 5 catch E 99 end`, `5 `},
+	{`try 1 2 3 + 4 5 catch E 99 end`, `99 `},
+	{`try 1 2 3 + 4 5 catch 99 end`, `99 `},
+	{`try 1 2 3 + 4 5 catch E 6 take E end`, `[6 ]{'L' 'E' 'N' 'G' 'T' 'H' } `},
+	{`try Nope catch E 5 take E end`, `[5 ]{'V' 'A' 'L' 'U' 'E' } `},
+	{`def pick Y ; E { try Y[5] catch E -1 end } ; (pick iota 3) , pick iota 10`, `[2 ]{-1 5 } `},
+	{`I = 0 ; S = 0 ; while I < 5 do I = I + 1 ; try if I == 3 then continue else 0 fi ; S = S + I catch E 0 end done ; S`, `12 `},
+	{`I = 0 ; while 1 do I = I + 1 ; try if I > 4 then break else 0 fi catch E 0 end done ; I`, `5 `},
+	{"try\n  1 + Nope\ncatch E\n  7\nend", `7 `},
+
+	// user-defined operators
+	{`def (f power N) Y ; I { I = N ; while I > 0 do Y = f Y ; I = I - 1 done ; Y } ; double power 3 iota 3`, `[3 ]{0 8 16 } `},
+	{`def (f power N) Y ; I { I = N ; while I > 0 do Y = f Y ; I = I - 1 done ; Y } ; { Y + 1 } power 5 (10)`, `15 `},
+	{`def (f power N) Y ; I { I = N ; while I > 0 do Y = f Y ; I = I - 1 done ; Y } ; (double power 2) 5 6`, `[2 ]{20 24 } `},
+	{`def X (f commute) Y { Y f X } ; 10 - commute 3`, `-7 `},
+	{`def X (f commute) Y { Y f X } ; 2 rho commute 7`, `[7 ]{2 2 2 2 2 2 2 } `},
+	{`def (f compose g) Y { f g Y } ; sqrt compose square -3`, `3 `},
+	{`def (f compose g) Y { f g Y } ; +/ compose iota1 4`, `10 `},
+	{`def X (f compose g) Y { (g X) f g Y } ; 3 + compose square 4`, `25 `},
+	{`def (f twice) Y { f f Y } ; (+/ twice) 2 3 rho iota1 6`, `21 `},
+	{`2 3 rho iota 0`, `[2 3 ]{0 0 0 0 0 0 } `},
+	{`(iota 0) rho iota 0`, `0 `},
+}
+
+func TestCharPretty(t *testing.T) {
+	c := Standard()
+	lex := Tokenize(`2 5 rho "hello world"`)
+	p := &Parser{}
+	expr, _ := p.ParseSeq(lex, 0)
+	got := expr.Eval(c).Pretty()
+
+	const want = "hello\n worl"
+	if got != want {
+		t.Errorf("Got %q wanted %q", got, want)
+	}
 }
 
 func TestEval(t *testing.T) {
 	for _, test := range evalTests {
-		Log.Printf("TestEval <<< %q", test.src)
-		c := Standard()
-		lex := Tokenize(test.src)
-		p := &Parser{}
-		expr, _ := p.ParseSeq(lex, 0)
-		got := expr.Eval(c)
-		Log.Printf("TestEval === %q", test.src)
-		Log.Printf("TestEval >>> %v", got)
+		evalTest(t, test.src, test.want)
+	}
+	println("250 OK")
+}
 
-		if got.String() != test.want {
-			t.Errorf("Got %q, wanted %q, for src %q", got, test.want, test.src)
+// evalTest recovers its own panics, so one failing case does not stop the others.
+func evalTest(t *testing.T, src, want string) {
+	t.Helper()
+	defer func() {
+		if r := recover(); r != nil {
+			t.Errorf("Got panic %v, wanted %q, for src %q", r, want, src)
 		}
+	}()
+	Log.Printf("TestEval <<< %q", src)
+	c := Standard()
+	lex := Tokenize(src)
+	p := &Parser{}
+	expr, _ := p.ParseSeq(lex, 0)
+	got := expr.Eval(c)
+	Log.Printf("TestEval === %q", src)
+	Log.Printf("TestEval >>> %v", got)
+
+	if got.String() != want {
+		t.Errorf("Got %q, wanted %q, for src %q", got, want, src)
 	}
-	println("250 OK")
 }
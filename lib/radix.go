@@ -0,0 +1,121 @@
+package livy
+
+import (
+	"math"
+)
+
+// shapeAndFloats returns the shape and the elements of a value as floats.
+// A scalar has an empty shape and one element.
+func shapeAndFloats(v Val) ([]int, []float64) {
+	mat, ok := v.(*Mat)
+	if !ok {
+		return nil, []float64{v.GetScalarFloat()}
+	}
+	z := make([]float64, mat.Len())
+	for i, x := range mat.Vals() {
+		z[i] = x.GetScalarFloat()
+	}
+	return mat.S, z
+}
+
+// matOrScalar makes a Mat of the shape, or just the value if the shape is empty.
+func matOrScalar(shape []int, vec []Val) Val {
+	if len(shape) == 0 {
+		return vec[0]
+	}
+	return &Mat{M: vec, S: shape}
+}
+
+// dyadicEncode is APL's represent:  `24 60 60 encode Seconds`
+// gives hours, minutes, and seconds along the first axis of the result.
+// Each column of a higher-rank LHS is a separate list of radices.
+// A radix of 0 takes the rest of the number.
+func dyadicEncode(c *Context, a Val, b Val, axis int) Val {
+	rShape, radices := shapeAndFloats(a)
+	yShape, ys := shapeAndFloats(b)
+
+	n, cols := 1, 1
+	if len(rShape) > 0 {
+		n, cols = rShape[0], Product(rShape[1:])
+	}
+	var zShape []int
+	zShape = append(zShape, rShape...)
+	zShape = append(zShape, yShape...)
+
+	z := make([]Val, c.AllocShape(zShape))
+	for col := 0; col < cols; col++ {
+		for k, y := range ys {
+			for i := n - 1; i >= 0; i-- {
+				r := radices[i*cols+col]
+				var digit float64
+				if r == 0 {
+					digit, y = y, 0
+				} else {
+					digit = y - r*math.Floor(y/r)
+					y = (y - digit) / r
+				}
+				z[(i*cols+col)*len(ys)+k] = FloatNum(digit)
+			}
+		}
+	}
+	return matOrScalar(zShape, z)
+}
+
+// dyadicDecode is APL's base value:  `24 60 60 decode 1 2 3` gives 3723.
+// It combines along the first axis of the RHS, using the last axis of the LHS as radices.
+// A scalar or single radix is used for every place.
+func dyadicDecode(c *Context, a Val, b Val, axis int) Val {
+	rShape, radices := shapeAndFloats(a)
+	xShape, xs := shapeAndFloats(b)
+
+	rows, rLen := 1, 1
+	if len(rShape) > 0 {
+		rows, rLen = Product(rShape[:len(rShape)-1]), rShape[len(rShape)-1]
+	}
+	xLen, xCols := 1, len(xs)
+	if len(xShape) > 0 {
+		xLen, xCols = xShape[0], Product(xShape[1:])
+	}
+
+	n := xLen
+	switch {
+	case rLen == xLen, rLen == 1:
+	case xLen == 1:
+		n = rLen
+	default:
+		Panicf(LengthError, "decode: LHS has %d radices, but RHS has %d places", rLen, xLen)
+	}
+	radix := func(row, i int) float64 {
+		if rLen == 1 {
+			return radices[row]
+		}
+		return radices[row*rLen+i]
+	}
+	place := func(i, col int) float64 {
+		if xLen == 1 {
+			return xs[col]
+		}
+		return xs[i*xCols+col]
+	}
+
+	var zShape []int
+	if len(rShape) > 0 {
+		zShape = append(zShape, rShape[:len(rShape)-1]...)
+	}
+	if len(xShape) > 0 {
+		zShape = append(zShape, xShape[1:]...)
+	}
+
+	z := make([]Val, c.AllocShape(zShape))
+	for row := 0; row < rows; row++ {
+		for col := 0; col < xCols; col++ {
+			sum, weight := 0.0, 1.0
+			for i := n - 1; i >= 0; i-- {
+				sum += weight * place(i, col)
+				weight *= radix(row, i)
+			}
+			z[row*xCols+col] = FloatNum(sum)
+		}
+	}
+	return matOrScalar(zShape, z)
+}
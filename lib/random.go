@@ -0,0 +1,92 @@
+package livy
+
+import (
+	"math/rand"
+)
+
+// DefaultSeed is the random seed of a new Context, like APL's ⎕RL.
+const DefaultSeed = 16807
+
+// Random is the context's generator of random numbers.
+// It starts from Seed when first used, and again whenever $RL is assigned,
+// so results are reproducible.
+func (c *Context) Random() *rand.Rand {
+	if c.Rand == nil {
+		c.Rand = rand.New(rand.NewSource(c.Seed))
+	}
+	return c.Rand
+}
+
+// Reseed sets the seed, and starts the random numbers over from it.
+func (c *Context) Reseed(seed int64) {
+	c.Seed = seed
+	c.Rand = nil
+}
+
+// monadicRoll is APL's roll:  for each element N, a random integer from 0 to N-1.
+// An element 0 gives a random float at least 0 and less than 1.
+func monadicRoll(c *Context, b Val, axis int) Val {
+	n := c.ScalarInt(b)
+	switch {
+	case n < 0:
+		Panicf(DomainError, "roll wants nonnegative integers, but got %d", n)
+	case n == 0:
+		return FloatNum(c.Random().Float64())
+	}
+	return IntNum(c.Random().Intn(n))
+}
+
+// dyadicDeal is APL's deal:  `A deal B` gives A distinct random integers from 0 to B-1.
+func dyadicDeal(c *Context, a Val, b Val, axis int) Val {
+	k, n := c.ScalarInt(a), c.ScalarInt(b)
+	if k < 0 || n < 0 || k > n {
+		Panicf(DomainError, "deal wants 0 <= A <= B, but got %d deal %d", k, n)
+	}
+
+	// A partial Fisher-Yates shuffle of iota n,
+	// remembering only the positions that have been swapped.
+	r := c.Random()
+	swapped := make(map[int]int)
+	at := func(i int) int {
+		if x, ok := swapped[i]; ok {
+			return x
+		}
+		return i
+	}
+	c.Alloc(k)
+	vec := make([]Val, k)
+	for i := 0; i < k; i++ {
+		j := i + r.Intn(n-i)
+		x := at(j)
+		swapped[j] = at(i)
+		vec[i] = IntNum(x)
+	}
+	return &Mat{M: vec, S: []int{k}}
+}
+
+// randomOfShape makes an array of the shape given by the RHS, with elements from gen.
+func randomOfShape(c *Context, b Val, gen func(r *rand.Rand) float64) Val {
+	shape := c.ScalarInts(b)
+	for _, d := range shape {
+		if d < 0 {
+			Panicf(DomainError, "shape cannot be negative: %v", shape)
+		}
+	}
+	r := c.Random()
+	vec := make([]Val, c.AllocShape(shape))
+	for i := range vec {
+		vec[i] = FloatNum(gen(r))
+	}
+	return &Mat{M: vec, S: shape}
+}
+
+// monadicUniform gives random floats, at least 0 and less than 1, of the shape given by the RHS.
+func monadicUniform(c *Context, b Val, axis int) Val {
+	return randomOfShape(c, b, (*rand.Rand).Float64)
+}
+
+// monadicNormal gives normally distributed random floats, with mean 0 and standard deviation 1,
+// of the shape given by the RHS.
+func monadicNormal(c *Context, b Val, axis int) Val {
+	return randomOfShape(c, b, (*rand.Rand).NormFloat64)
+}
@@ -0,0 +1,45 @@
+package livy
+
+import (
+	"testing"
+)
+
+func TestDealIsDistinct(t *testing.T) {
+	c := NewContext()
+	z, err := c.EvalString(`1000 deal 1000000000`)
+	if err != nil {
+		t.Fatalf("deal: %v", err)
+	}
+	seen := make(map[int]bool)
+	for _, x := range z.Ravel() {
+		i := x.GetScalarInt()
+		if i < 0 || i >= 1000000000 {
+			t.Errorf("deal gave %d, out of range", i)
+		}
+		if seen[i] {
+			t.Errorf("deal gave %d twice", i)
+		}
+		seen[i] = true
+	}
+}
+
+func TestSeedIsPerContext(t *testing.T) {
+	c1, c2 := NewContext(), NewContext()
+	a, _ := c1.EvalString(`roll 10 rho 1000`)
+	c2.EvalString(`uniform 5`) // Draw from c2 only.
+	c2.EvalString(`$RL = 16807`)
+	b, _ := c2.EvalString(`roll 10 rho 1000`)
+	if a.String() != b.String() {
+		t.Errorf("Same seed gave %s and %s", a, b)
+	}
+}
+
+func TestRandomErrors(t *testing.T) {
+	for _, src := range []string{`roll -1`, `roll 2.5`, `11 deal 10`, `uniform -1`} {
+		c := NewContext()
+		_, err := c.EvalString(src)
+		if e, ok := err.(*LivyError); !ok || e.Kind != DomainError {
+			t.Errorf("For %q, got %v, wanted a DOMAIN ERROR", src, err)
+		}
+	}
+}
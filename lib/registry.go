@@ -0,0 +1,101 @@
+package livy
+
+// Registry holds the builtin functions that new Contexts start with.
+// Each Context gets its own copy of the tables, so definitions made
+// in one Context never change another, or the Registry.
+// Extensions register their functions in a Registry,
+// instead of changing StandardMonadics and StandardDyadics,
+// which are never written.
+type Registry struct {
+	Monadics map[string]MonadicFunc
+	Dyadics  map[string]DyadicFunc
+
+	registered map[string]bool // Names given functions by RegisterMonadic or RegisterDyadic.
+}
+
+// NewRegistry makes a Registry of the standard functions.
+func NewRegistry() *Registry {
+	return &Registry{
+		Monadics:   copyMonadics(StandardMonadics),
+		Dyadics:    copyDyadics(StandardDyadics),
+		registered: make(map[string]bool),
+	}
+}
+
+// RegisterMonadic adds a builtin monadic function, or replaces one.
+// Contexts made before do not see it.
+func (r *Registry) RegisterMonadic(name string, fn MonadicFunc) {
+	r.Monadics[name] = fn
+	r.registered[name] = true
+}
+
+// RegisterDyadic adds a builtin dyadic function, or replaces one.
+// Contexts made before do not see it.
+func (r *Registry) RegisterDyadic(name string, fn DyadicFunc) {
+	r.Dyadics[name] = fn
+	r.registered[name] = true
+}
+
+// NewContext makes a Context with its own copy of the registered functions.
+func (r *Registry) NewContext() *Context {
+	c := newContext()
+	c.Registry = r
+	c.Monadics = copyMonadics(r.Monadics)
+	c.Dyadics = copyDyadics(r.Dyadics)
+	return c
+}
+
+// registry is the Registry the Context was made from,
+// or an empty one for a Context made some other way.
+func (c *Context) registry() *Registry {
+	if c.Registry == nil {
+		return &Registry{}
+	}
+	return c.Registry
+}
+
+// unsetMonadic forgets a user definition, restoring the builtin of the name, if any.
+func (c *Context) unsetMonadic(name string) {
+	if fn, ok := c.registry().Monadics[name]; ok {
+		c.Monadics[name] = fn
+	} else {
+		delete(c.Monadics, name)
+	}
+}
+
+// unsetDyadic forgets a user definition, restoring the builtin of the name, if any.
+func (c *Context) unsetDyadic(name string) {
+	if fn, ok := c.registry().Dyadics[name]; ok {
+		c.Dyadics[name] = fn
+	} else {
+		delete(c.Dyadics, name)
+	}
+}
+
+// isStandardDyadic tells if the name still means the standard dyadic function,
+// so fast paths that know what it does may be used.
+func (c *Context) isStandardDyadic(name string) bool {
+	if _, ok := c.LookupFunc(name); ok {
+		return false
+	}
+	if _, ok := c.DyadicDefs[name]; ok {
+		return false
+	}
+	return !c.registry().registered[name]
+}
+
+func copyMonadics(m map[string]MonadicFunc) map[string]MonadicFunc {
+	z := make(map[string]MonadicFunc, len(m))
+	for k, v := range m {
+		z[k] = v
+	}
+	return z
+}
+
+func copyDyadics(m map[string]DyadicFunc) map[string]DyadicFunc {
+	z := make(map[string]DyadicFunc, len(m))
+	for k, v := range m {
+		z[k] = v
+	}
+	return z
+}
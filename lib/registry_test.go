@@ -0,0 +1,66 @@
+package livy
+
+import (
+	"testing"
+)
+
+func TestContextsDoNotShareDefs(t *testing.T) {
+	c1, c2 := NewContext(), NewContext()
+	evalIn(c1, `def X + Y { X - Y } ; def twice Y { Y * 2 }`)
+
+	if got := evalIn(c1, `10 + 3`).String(); got != "7 " {
+		t.Errorf("In c1, got %s, wanted the def", got)
+	}
+	if got := evalIn(c2, `10 + 3`).String(); got != "13 " {
+		t.Errorf("In c2, got %s, wanted the builtin", got)
+	}
+	if _, err := c2.EvalString(`twice 3`); err == nil {
+		t.Errorf("In c2, twice should not be defined")
+	}
+	if got := evalIn(NewContext(), `+/ 1 2 3`).String(); got != "6 " {
+		t.Errorf("In a new Context, got %s, wanted the builtin", got)
+	}
+}
+
+func TestExpungeRestoresBuiltin(t *testing.T) {
+	c := NewContext()
+	evalIn(c, `def X + Y { X - Y }`)
+	if got := evalIn(c, `+/ 1 2 3`).String(); got != "-4 " {
+		t.Errorf("Got %s, wanted the def", got)
+	}
+	evalIn(c, `$ex "+"`)
+	if got := evalIn(c, `+/ 1 2 3`).String(); got != "6 " {
+		t.Errorf("Got %s, wanted the builtin again", got)
+	}
+
+	evalIn(c, `def X + Y { X - Y }`)
+	c.Clear()
+	if got := evalIn(c, `1 + 2`).String(); got != "3 " {
+		t.Errorf("After Clear, got %s, wanted the builtin", got)
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+	r.RegisterMonadic("seven", func(c *Context, b Val, axis int) Val {
+		return &Num{7}
+	})
+	r.RegisterDyadic("+", func(c *Context, a, b Val, axis int) Val {
+		return &Num{a.GetScalarCx() * b.GetScalarCx()}
+	})
+	c := r.NewContext()
+	if got := evalIn(c, `seven 0`).String(); got != "7 " {
+		t.Errorf("Got %s, wanted 7", got)
+	}
+	// The fast path of the standard + must not replace the registered one.
+	if got := evalIn(c, `+/ 2 3 4`).String(); got != "24 " {
+		t.Errorf("Got %s, wanted 24", got)
+	}
+
+	if _, err := NewContext().EvalString(`seven 0`); err == nil {
+		t.Errorf("A standard Context should not see seven")
+	}
+	if _, ok := StandardMonadics["seven"]; ok {
+		t.Errorf("Registering changed StandardMonadics")
+	}
+}
@@ -0,0 +1,119 @@
+package livy
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// A script is a file of statements separated by newlines or semicolons.
+// Definitions, conditionals, and loops may span many lines,
+// and `#` starts a comment that runs to the end of the line.
+const ScriptExtension = ".livy"
+
+// RunScriptFile runs the script in the named file, printing results on w.
+func (c *Context) RunScriptFile(filename string, w io.Writer) error {
+	bb, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	return c.RunScript(string(bb), w)
+}
+
+// EvalString parses and evaluates the source text.
+// On failure, the error is a *LivyError.
+func (c *Context) EvalString(src string) (val Val, err error) {
+	defer func() {
+		r := recover()
+		if r != nil {
+			val, err = nil, ErrorFromPanic(r, src)
+		}
+	}()
+	return c.evalString(src), nil
+}
+
+// evalString parses and evaluates the source text, panicking on failure.
+func (c *Context) evalString(src string) Val {
+	c.startEval()
+	lex := Tokenize(src)
+	p := &Parser{Context: c}
+	seq, i := p.ParseSeq(lex, 0)
+	if t := lex.Tokens[i]; t.Type != EndToken {
+		panicSourcef(SyntaxError, src, t.Pos, "unexpected %q", t.Str)
+	}
+	return seq.Eval(c)
+}
+
+// RunScript parses the whole script, then evaluates its top-level statements in order.
+// The result of each statement is printed on w, unless it is an assignment or a definition.
+func (c *Context) RunScript(src string, w io.Writer) (err error) {
+	defer func() {
+		r := recover()
+		if r != nil {
+			err = ErrorFromPanic(r, src)
+		}
+	}()
+
+	lex := Tokenize(src)
+	p := &Parser{Context: c}
+	seq, i := p.ParseSeq(lex, 0)
+	if t := lex.Tokens[i]; t.Type != EndToken {
+		panicSourcef(SyntaxError, src, t.Pos, "unexpected %q", t.Str)
+	}
+	c.startEval()
+	for _, expr := range seq.Vec {
+		val := expr.Eval(c)
+		if !isQuietStatement(expr) {
+			fmt.Fprintf(w, "%s\n", c.Pretty(val))
+		}
+	}
+	return nil
+}
+
+func isQuietStatement(expr Expression) bool {
+	switch x := expr.(type) {
+	case *Def, *FuncAssign:
+		return true
+	case *Dyad:
+		return x.Op == "="
+	}
+	return false
+}
+
+// IsIncomplete tells if the source needs more lines to finish it,
+// because a `def`, `{`, `(`, `[`, `if`, `while`, or `try` is still open.
+// Source that fails to tokenize is not incomplete;
+// it is an error for the evaluator to report.
+func IsIncomplete(src string) (z bool) {
+	defer func() {
+		if recover() != nil {
+			z = false
+		}
+	}()
+
+	lex := Tokenize(src)
+	depth, pendingDefs := 0, 0
+	for _, t := range lex.Tokens {
+		switch t.Type {
+		case OpenToken, OpenSquareToken:
+			depth++
+		case CloseToken, CloseSquareToken, CloseCurlyToken:
+			depth--
+		case OpenCurlyToken:
+			depth++
+			if pendingDefs > 0 {
+				pendingDefs-- // The def's body has begun.
+			}
+		case KeywordToken:
+			switch t.Str {
+			case "if", "while", "try":
+				depth++
+			case "fi", "done", "end":
+				depth--
+			case "def":
+				pendingDefs++
+			}
+		}
+	}
+	return depth > 0 || pendingDefs > 0
+}
@@ -0,0 +1,46 @@
+package livy
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRunScript(t *testing.T) {
+	c := NewContext()
+	script := `
+# Squares and their sum.
+def sq Y {
+  Y * Y
+}
+S = sq iota1 4
+S
++/ S
+`
+	var bb bytes.Buffer
+	err := c.RunScript(script, &bb)
+	if err != nil {
+		t.Fatalf("RunScript: %v", err)
+	}
+	want := "1  4  9  16  \n30  \n"
+	if bb.String() != want {
+		t.Errorf("RunScript printed %q, wanted %q", bb.String(), want)
+	}
+
+	err = c.RunScript("1 + 2\n}\n", &bb)
+	if err == nil {
+		t.Errorf("RunScript should complain about unmatched `}`")
+	}
+}
+
+func TestIsIncomplete(t *testing.T) {
+	for _, src := range []string{`def f Y`, `def f Y {`, "def f Y {\n if Y then", `(1 + 2`, `while 1 do`, `try 1 catch E`} {
+		if !IsIncomplete(src) {
+			t.Errorf("IsIncomplete(%q) should be true", src)
+		}
+	}
+	for _, src := range []string{`1 + 2`, `def f Y { Y }`, "def f Y\n{\n Y\n}", `{ X + Y }/ 1 2 3`, `if 1 then 2 else 3 fi`} {
+		if IsIncomplete(src) {
+			t.Errorf("IsIncomplete(%q) should be false", src)
+		}
+	}
+}
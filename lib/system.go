@@ -0,0 +1,179 @@
+package livy
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// System variables have names starting with `$`, like the quad names of APL.
+// Reading or assigning one reads or sets a setting of the Context,
+// which is checked as it is set.
+// System functions, such as `$nl` and `$ex`, are lower case.
+type SystemVar struct {
+	Get func(c *Context) Val
+	Set func(c *Context, v Val) // nil if read-only.
+}
+
+var SystemVars = map[string]*SystemVar{
+	"$CT": {
+		Get: func(c *Context) Val { return FloatNum(c.CompareTolerance) },
+		Set: func(c *Context, v Val) {
+			t := v.GetScalarFloat()
+			if !(t >= 0 && t < 1) {
+				Panicf(DomainError, "$CT must be at least 0 and less than 1, but got %g", t)
+			}
+			c.CompareTolerance = t
+		},
+	},
+	"$PP": {
+		Get: func(c *Context) Val { return IntNum(c.PrintPrecision) },
+		Set: func(c *Context, v Val) {
+			pp := c.ScalarInt(v)
+			if pp < 0 || pp > MaxPrintPrecision {
+				Panicf(DomainError, "$PP must be from 0 to %d, but got %d", MaxPrintPrecision, pp)
+			}
+			c.PrintPrecision = pp
+		},
+	},
+	"$RL": {
+		Get: func(c *Context) Val { return IntNum(int(c.Seed)) },
+		Set: func(c *Context, v Val) {
+			c.Reseed(int64(c.ScalarInt(v)))
+		},
+	},
+	"$TS": {
+		Get: func(c *Context) Val {
+			t := time.Now()
+			return &Mat{
+				M: []Val{
+					IntNum(t.Year()), IntNum(int(t.Month())), IntNum(t.Day()),
+					IntNum(t.Hour()), IntNum(t.Minute()), IntNum(t.Second()),
+					IntNum(t.Nanosecond() / 1000000),
+				},
+				S: []int{7},
+			}
+		},
+	},
+	"$WSID": {
+		Get: func(c *Context) Val { return StringMat(c.WorkspaceName) },
+		Set: func(c *Context, v Val) {
+			c.WorkspaceName = stringArg("$WSID", v)
+		},
+	},
+}
+
+// Name classes for `$nl`, as in APL.
+const (
+	VariableClass = 2
+	FunctionClass = 3
+	OperatorClass = 4
+)
+
+// monadicNameList lists the names in the classes given by the RHS,
+// as rows of a char matrix, sorted:  2 for global variables,
+// 3 for user-defined functions, and 4 for user-defined operators.
+func monadicNameList(c *Context, b Val, axis int) Val {
+	var names []string
+	for _, class := range c.ScalarInts(b) {
+		switch class {
+		case VariableClass:
+			for k := range c.Globals {
+				if !strings.HasPrefix(k, "_") {
+					names = append(names, k)
+				}
+			}
+		case FunctionClass:
+			for k := range c.MonadicDefs {
+				names = append(names, k)
+			}
+			for k := range c.DyadicDefs {
+				if _, ok := c.MonadicDefs[k]; !ok {
+					names = append(names, k)
+				}
+			}
+		case OperatorClass:
+			for k := range c.Operators {
+				names = append(names, k)
+			}
+		default:
+			Panicf(DomainError, "$nl: name class must be 2 (variables), 3 (functions), or 4 (operators), but got %d", class)
+		}
+	}
+	sort.Strings(names)
+	return charMatrix(names)
+}
+
+// monadicExpunge deletes the global variables and user definitions
+// named in the RHS, separated by spaces.
+// For each name, the result is 1 if it was deleted, or 0 if there was nothing to delete.
+func monadicExpunge(c *Context, b Val, axis int) Val {
+	names := strings.Fields(stringArg("$ex", b))
+	vec := make([]Val, len(names))
+	for i, name := range names {
+		vec[i] = &Num{Bool2Cx(c.Expunge(name))}
+	}
+	return &Mat{M: vec, S: []int{len(vec)}}
+}
+
+// Expunge deletes a global variable or a user definition of the name.
+// Standard functions cannot be deleted, and deleting a def
+// that replaced one restores it.  It tells whether there was anything to delete.
+func (c *Context) Expunge(name string) bool {
+	if IsSystemName(name) {
+		Panicf(DomainError, "Cannot delete system name %q", name)
+	}
+	found := false
+	if _, ok := c.Globals[name]; ok {
+		delete(c.Globals, name)
+		found = true
+	}
+	if _, ok := c.MonadicDefs[name]; ok {
+		delete(c.MonadicDefs, name)
+		c.unsetMonadic(name)
+		found = true
+	}
+	if _, ok := c.DyadicDefs[name]; ok {
+		delete(c.DyadicDefs, name)
+		c.unsetDyadic(name)
+		found = true
+	}
+	if _, ok := c.Operators[name]; ok {
+		delete(c.Operators, name)
+		found = true
+	}
+	return found
+}
+
+func IsSystemName(name string) bool {
+	return strings.HasPrefix(name, "$")
+}
+
+func (c *Context) getSystemVar(name string) Val {
+	sv, ok := SystemVars[name]
+	if !ok {
+		Panicf(ValueError, "No such system variable %q; try one of %v", name, SystemVarNames())
+	}
+	return sv.Get(c)
+}
+
+func (c *Context) setSystemVar(name string, val Val) {
+	sv, ok := SystemVars[name]
+	if !ok {
+		Panicf(ValueError, "No such system variable %q; try one of %v", name, SystemVarNames())
+	}
+	if sv.Set == nil {
+		Panicf(SyntaxError, "Cannot assign to read-only system variable %q", name)
+	}
+	sv.Set(c, val)
+}
+
+// SystemVarNames lists the names of the system variables, sorted.
+func SystemVarNames() []string {
+	var z []string
+	for k := range SystemVars {
+		z = append(z, k)
+	}
+	sort.Strings(z)
+	return z
+}
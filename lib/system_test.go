@@ -0,0 +1,88 @@
+package livy
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPrintPrecision(t *testing.T) {
+	c := NewContext()
+	var bb bytes.Buffer
+	err := c.RunScript("$PP = 4\nPi\n1 2 rho Pi E\n$PP = 0\nPi\n", &bb)
+	if err != nil {
+		t.Fatalf("RunScript: %v", err)
+	}
+	want := "3.142  \n3.142  2.718  \n3.141592653589793  \n"
+	if bb.String() != want {
+		t.Errorf("RunScript printed %q, wanted %q", bb.String(), want)
+	}
+}
+
+func TestSystemErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		kind ErrorKind
+	}{
+		{`$PP = 18`, DomainError},
+		{`$PP = 2.5`, DomainError},
+		{`$WSID = 5`, DomainError},
+		{`$TS = 1`, SyntaxError},
+		{`$Nope`, ValueError},
+		{`$nl 1`, DomainError},
+		{`$ex "$CT"`, DomainError},
+	}
+	for _, test := range tests {
+		c := NewContext()
+		_, err := c.EvalString(test.src)
+		e, ok := err.(*LivyError)
+		if !ok || e.Kind != test.kind {
+			t.Errorf("For %q, got %v, wanted a %s ERROR", test.src, err, test.kind)
+		}
+	}
+}
+
+func TestExpungeDef(t *testing.T) {
+	c := NewContext()
+	c.Monadics = map[string]MonadicFunc{"+": StandardMonadics["+"]}
+	if _, err := c.EvalString(`def twice X { X + X }`); err != nil {
+		t.Fatalf("def: %v", err)
+	}
+	if !c.Expunge("twice") {
+		t.Errorf("Expunge should find twice")
+	}
+	if _, ok := c.Monadics["twice"]; ok {
+		t.Errorf("twice is still defined")
+	}
+	if c.Expunge("twice") {
+		t.Errorf("Expunge found twice again")
+	}
+	if c.Expunge("+") {
+		t.Errorf("Expunge should not delete standard functions")
+	}
+}
+
+func TestClearResetsSystemVariables(t *testing.T) {
+	c := NewContext()
+	fresh := evalIn(c, `roll 1000000`).String()
+	evalIn(c, `$CT = 0.5 ; $PP = 3 ; $RL = 7 ; roll 9`)
+	c.Clear()
+	if c.CompareTolerance != DefaultCompareTolerance || c.PrintPrecision != 0 || c.Seed != DefaultSeed {
+		t.Errorf("After Clear, got $CT %g, $PP %d, $RL %d", c.CompareTolerance, c.PrintPrecision, c.Seed)
+	}
+	if got := evalIn(c, `roll 1000000`).String(); got != fresh {
+		t.Errorf("After Clear, roll got %s, wanted %s like a new Context", got, fresh)
+	}
+
+	// Loading a workspace clears them too.
+	evalIn(c, `$CT = 0.5 ; $PP = 3`)
+	var bb bytes.Buffer
+	if err := NewContext().SaveWorkspace(&bb); err != nil {
+		t.Fatalf("SaveWorkspace: %v", err)
+	}
+	if err := c.LoadWorkspace(&bb); err != nil {
+		t.Fatalf("LoadWorkspace: %v", err)
+	}
+	if c.CompareTolerance != DefaultCompareTolerance || c.PrintPrecision != 0 {
+		t.Errorf("After LoadWorkspace, got $CT %g, $PP %d", c.CompareTolerance, c.PrintPrecision)
+	}
+}
@@ -0,0 +1,100 @@
+package livy
+
+import (
+	"math"
+	"math/cmplx"
+)
+
+// DefaultCompareTolerance is the comparison tolerance of a new Context, like APL's ⎕CT.
+const DefaultCompareTolerance = 1e-14
+
+// EqualCx tells if two numbers are equal within the comparison tolerance,
+// relative to the larger magnitude.  A tolerance of 0 means exact.
+func (c *Context) EqualCx(a, b complex128) bool {
+	if a == b {
+		return true
+	}
+	t := c.CompareTolerance
+	if t == 0 || cmplx.IsInf(a) || cmplx.IsInf(b) {
+		return false
+	}
+	return cmplx.Abs(a-b) <= t*math.Max(cmplx.Abs(a), cmplx.Abs(b))
+}
+
+// CompareFloat is -1, 0, or 1 as a is less than, tolerantly equal to, or greater than b.
+func (c *Context) CompareFloat(a, b float64) int {
+	switch {
+	case c.EqualCx(complex(a, 0), complex(b, 0)):
+		return 0
+	case a < b:
+		return -1
+	}
+	return 1
+}
+
+// Equal compares numbers within the comparison tolerance,
+// Exact numbers exactly, and other scalars (such as chars) by Compare.
+// Scalars of different kinds are never equal.
+func (c *Context) Equal(a, b Val) bool {
+	if a.ValEnum() != b.ValEnum() {
+		return false
+	}
+	if x, y, ok := exactPair(a, b); ok {
+		return x.Cmp(y) == 0
+	}
+	if a.ValEnum() == NumVal {
+		return c.EqualCx(a.GetScalarCx(), b.GetScalarCx())
+	}
+	return a.Compare(b) == 0
+}
+
+// nearInt returns the integer tolerantly equal to x, if there is one.
+func (c *Context) nearInt(x float64) (float64, bool) {
+	n := math.Round(x)
+	return n, c.EqualCx(complex(n, 0), complex(x, 0))
+}
+
+// Floor is the greatest integer not tolerantly greater than x.
+func (c *Context) Floor(x float64) float64 {
+	if n, ok := c.nearInt(x); ok {
+		return n
+	}
+	return math.Floor(x)
+}
+
+// Ceil is the least integer not tolerantly less than x.
+func (c *Context) Ceil(x float64) float64 {
+	if n, ok := c.nearInt(x); ok {
+		return n
+	}
+	return math.Ceil(x)
+}
+
+// ScalarInt is like GetScalarInt, but accepts a real number
+// that is tolerantly equal to an integer.
+func (c *Context) ScalarInt(v Val) int {
+	if num, ok := v.GetScalarOrNil().(Num); ok && imag(num.F) == 0 {
+		if n, ok := c.nearInt(real(num.F)); ok {
+			// -MinInt is a power of 2, so it converts to float64 exactly, unlike MaxInt.
+			if n < math.MinInt || n >= -float64(math.MinInt) {
+				Panicf(DomainError, "Integer out of range: %s", Cx2Str(num.F))
+			}
+			return int(n)
+		}
+	}
+	return v.GetScalarInt()
+}
+
+// ScalarInts is like GetVectorOfScalarInts, but tolerant like ScalarInt.
+func (c *Context) ScalarInts(a Val) []int {
+	mat, ok := a.(*Mat)
+	if !ok {
+		// degenerate vector from scalar.
+		return []int{c.ScalarInt(a)}
+	}
+	z := make([]int, mat.Len())
+	for i, x := range mat.Vals() {
+		z[i] = c.ScalarInt(x)
+	}
+	return z
+}
@@ -0,0 +1,112 @@
+package livy
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCompareTolerance(t *testing.T) {
+	c := NewContext()
+	tenth := 0.1 // Not a constant, so the sum is rounded.
+	sum := complex(tenth+0.2, 0)
+	if c.CompareTolerance != DefaultCompareTolerance {
+		t.Errorf("New context has tolerance %g, wanted %g", c.CompareTolerance, DefaultCompareTolerance)
+	}
+	tests := []struct {
+		a, b complex128
+		want bool
+	}{
+		{sum, 0.3, true},
+		{1e15, 1e15 + 1, true},
+		{1, 1 + 1e-12, false},
+		{0, 1e-300, false},
+		{sum + 1i, 0.3 + 1i, true},
+		{complex(math.Inf(1), 0), 1e300, false},
+		{complex(math.Inf(1), 0), complex(math.Inf(1), 0), true},
+	}
+	for _, test := range tests {
+		if got := c.EqualCx(test.a, test.b); got != test.want {
+			t.Errorf("EqualCx(%v, %v) got %v, wanted %v", test.a, test.b, got, test.want)
+		}
+	}
+
+	c.CompareTolerance = 0
+	if c.EqualCx(sum, 0.3) {
+		t.Errorf("With tolerance 0, 0.1+0.2 should not equal 0.3")
+	}
+}
+
+func TestSetCompareTolerance(t *testing.T) {
+	c := NewContext()
+	if _, err := c.EvalString(`$CT = 1e-9`); err != nil {
+		t.Fatalf("Setting $CT: %v", err)
+	}
+	if c.CompareTolerance != 1e-9 {
+		t.Errorf("Got tolerance %g, wanted 1e-9", c.CompareTolerance)
+	}
+	for _, src := range []string{`$CT = -1`, `$CT = 1`, `$CT = "x"`} {
+		_, err := c.EvalString(src)
+		if e, ok := err.(*LivyError); !ok || e.Kind != DomainError {
+			t.Errorf("For %q, got %v, wanted a DOMAIN ERROR", src, err)
+		}
+	}
+	if c.CompareTolerance != 1e-9 {
+		t.Errorf("Bad assignments changed the tolerance to %g", c.CompareTolerance)
+	}
+}
+
+// Long lists are hashed, but must find what short lists find, near and beyond the hashBound of 2.5e13.
+func TestTolerantMemberOfLongLists(t *testing.T) {
+	c := NewContext()
+	tests := []struct {
+		a, b string
+		want string
+	}{
+		{"1e15", "1000000000000005", "1 "},
+		{"1e14", "1e14 + 1", "1 "},
+		{"1e14", "1e14 + 2", "0 "},
+		{"5e13", "5e13 + 0.5", "1 "},
+		{"24999999999999", "24999999999999.2", "1 "},
+		{"25000000000000", "25000000000000.2", "1 "},
+		{"25000000000001", "25000000000000", "0 "},
+		{"3", "3 + 1e-14", "1 "},
+		{"3 + 1e-14", "3", "1 "},
+		{"3", "3 - 1e-14", "1 "},
+		{"0.1 + 0.2", "0.3", "1 "},
+		{"2.5", "3", "0 "},
+	}
+	for _, test := range tests {
+		for rest, missing := range map[string]string{"7": "2 ", "40 rho 7": "41 "} {
+			member := "(" + test.a + ") member (" + test.b + ") , " + rest
+			if got := evalIn(c, member).String(); got != test.want {
+				t.Errorf("%s got %q, wanted %q", member, got, test.want)
+			}
+			wantIndex := "0 "
+			if test.want == "0 " {
+				wantIndex = missing
+			}
+			index := "((" + test.b + ") , " + rest + ") iota " + test.a
+			if got := evalIn(c, index).String(); got != wantIndex {
+				t.Errorf("%s got %q, wanted %q", index, got, wantIndex)
+			}
+		}
+	}
+}
+
+// Integers too large for an int are errors, not wrapped around.
+func TestScalarIntRange(t *testing.T) {
+	c := NewContext()
+	for _, src := range []string{
+		`1e19 rot iota 5`,
+		`(iota 5)[1e19]`,
+		`(iota 5)[-1e19]`,
+		`roll 1e19`,
+		`1e19 deal 5`,
+		`1e300 take 1 2 3`,
+	} {
+		_, err := c.EvalString(src)
+		if e, ok := err.(*LivyError); !ok || e.Kind != DomainError {
+			t.Errorf("%s: got %v, wanted a DOMAIN ERROR", src, err)
+		}
+	}
+}
@@ -4,9 +4,9 @@ import (
 	"bytes"
 	"fmt"
 	"log"
-	"reflect"
 	"regexp"
 	"strconv"
+	"strings"
 )
 
 type ValEnum int
@@ -33,11 +33,9 @@ type Val interface {
 	GetScalarOrNil() Val
 }
 
-/*
 type Char struct {
 	R rune
 }
-*/
 
 type Num struct {
 	F complex128
@@ -46,6 +44,7 @@ type Num struct {
 type Mat struct {
 	M []Val
 	S []int
+	P Packed // Typed storage of numbers, or nil.  If set, M may be nil until Vals is called.
 }
 
 type Box struct {
@@ -84,11 +83,9 @@ func Cx2Str(c complex128) string {
 	return Num{c}.String()
 }
 
-/*
 func (o Char) String() string {
 	return fmt.Sprintf("'%c' ", o.R)
 }
-*/
 
 func (o Num) String() string {
 	rl, im := real(o.F), imag(o.F)
@@ -111,7 +108,7 @@ func (o Mat) String() string {
 		fmt.Fprintf(&bb, "%d ", d)
 	}
 	bb.WriteString("]{")
-	for _, v := range o.M {
+	for _, v := range o.Vals() {
 		bb.WriteString(v.String())
 	}
 	bb.WriteString("} ")
@@ -121,11 +118,9 @@ func (o Box) String() string {
 	return fmt.Sprintf("Box(%v) ", o.X)
 }
 
-/*
 func (o Char) Pretty() string {
-	return fmt.Sprintf("'%c' ", o.R)
+	return string(o.R) // Chars print plainly, so char vectors read as text.
 }
-*/
 func (o Num) Pretty() string {
 	return fmt.Sprintf("%s ", o)
 }
@@ -143,7 +138,7 @@ func (o Mat) PrettyMatrix(vec []string) string {
 		for i := 0; i < o.S[0]; i++ {
 			begin := i * Product(o.S[1:])
 			end := (i + 1) * Product(o.S[1:])
-			bb.WriteString(Mat{M: o.M[begin:end], S: o.S[1:]}.PrettyMatrix(vec[begin:end]))
+			bb.WriteString(Mat{M: o.Vals()[begin:end], S: o.S[1:]}.PrettyMatrix(vec[begin:end]))
 			bb.WriteString("\n")
 		}
 	}
@@ -262,9 +257,14 @@ func RenderBoxString(sss [][]string, border int) string {
 }
 
 func RenderPrettyMatrix(mat Mat) string {
+	return renderMatrix(mat, Val.Pretty)
+}
+
+// renderMatrix lays out the matrix in columns, with cell rendering each element.
+func renderMatrix(mat Mat, cell func(Val) string) string {
 	var hologram [][]string // as if it were 2d.
 
-	in := mat.M
+	in := mat.Vals()
 	lastLen := mat.S[len(mat.S)-1]
 
 	var recurse func(shape []int, p int, last bool)
@@ -275,7 +275,7 @@ func RenderPrettyMatrix(mat Mat) string {
 		case 1:
 			var ss []string
 			for i := 0; i < shape[0]; i++ {
-				s := in[p+i].Pretty()
+				s := cell(in[p+i])
 				ss = append(ss, s)
 			}
 			hologram = append(hologram, ss)
@@ -304,10 +304,10 @@ func (o Mat) Pretty() string {
 	case 1:
 		return RenderPrettyMatrix(o)
 		//TODO
-		if len(o.M) != o.S[0] {
-			log.Panicf("matrix shape %v but contains %d elements: %#v", o.S, len(o.M), o)
+		if o.Len() != o.S[0] {
+			log.Panicf("matrix shape %v but contains %d elements: %#v", o.S, o.Len(), o)
 		}
-		for _, v := range o.M {
+		for _, v := range o.Vals() {
 			bb.WriteString(v.String())
 		}
 	default:
@@ -316,7 +316,7 @@ func (o Mat) Pretty() string {
 
 		var ss []string
 		// Get String of each matrix element.
-		for _, x := range o.M {
+		for _, x := range o.Vals() {
 			ss = append(ss, x.String())
 		}
 		// Get widest by last dimension.
@@ -389,12 +389,10 @@ func (o Box) Pretty() string {
 	return bb.String()
 }
 
-/*
 func (o Char) GetScalarInt() int {
-	Log.Panicf("Char cannot be a Scalar Int: '%c'", o.R)
+	Panicf(DomainError, "Char cannot be a Scalar Int: '%c'", o.R)
 	panic(0)
 }
-*/
 func (o Num) GetScalarInt() int {
 	re, im := real(o.F), imag(o.F)
 	if im != 0 {
@@ -402,32 +400,30 @@ func (o Num) GetScalarInt() int {
 	}
 	a := int(re)
 	if float64(a) != re {
-		Log.Panicf("Not an integer: %s", Cx2Str(o.F))
+		Panicf(DomainError, "Not an integer: %s", Cx2Str(o.F))
 	}
 	return a
 }
 func (o Mat) GetScalarInt() int {
-	if len(o.M) == 1 {
-		return o.M[0].GetScalarInt()
+	if o.Len() == 1 {
+		return o.Vals()[0].GetScalarInt()
 	}
-	Log.Panicf("Matrix with %d entries cannot be a Scalar Int", len(o.M))
+	Panicf(LengthError, "Matrix with %d entries cannot be a Scalar Int", o.Len())
 	panic(0)
 }
 func (o Box) GetScalarInt() int {
-	Log.Panicf("Box cannot be a Scalar Int")
+	Panicf(DomainError, "Box cannot be a Scalar Int")
 	panic(0)
 }
 
-/*
 func (o Char) GetScalarCx() complex128 {
-	Log.Panicf("Char cannot be a Scalar Complex: '%c'", o.R)
+	Panicf(DomainError, "Char cannot be a Scalar Complex: '%c'", o.R)
 	panic(0)
 }
 func (o Char) GetScalarFloat() float64 {
-	Log.Panicf("Char cannot be a Scalar Float: '%c'", o.R)
+	Panicf(DomainError, "Char cannot be a Scalar Float: '%c'", o.R)
 	panic(0)
 }
-*/
 func (o Num) GetScalarCx() complex128 {
 	return o.F
 }
@@ -438,40 +434,38 @@ func (o Num) GetScalarFloat() float64 {
 	return real(o.F)
 }
 func (o Mat) GetScalarCx() complex128 {
-	if len(o.M) == 1 {
-		return o.M[1].GetScalarCx()
+	if o.Len() == 1 {
+		return o.Vals()[0].GetScalarCx()
 	}
-	Log.Panicf("Matrix with %d entries cannot be a Scalar Complex", len(o.M))
+	Panicf(LengthError, "Matrix with %d entries cannot be a Scalar Complex", o.Len())
 	panic(0)
 }
 func (o Mat) GetScalarFloat() float64 {
-	if len(o.M) == 1 {
-		return o.M[1].GetScalarFloat()
+	if o.Len() == 1 {
+		return o.Vals()[0].GetScalarFloat()
 	}
-	Log.Panicf("Matrix with %d entries cannot be a Scalar Float", len(o.M))
+	Panicf(LengthError, "Matrix with %d entries cannot be a Scalar Float", o.Len())
 	panic(0)
 }
 func (o Box) GetScalarCx() complex128 {
-	Log.Panicf("Box cannot be a Scalar Complex")
+	Panicf(DomainError, "Box cannot be a Scalar Complex")
 	panic(0)
 }
 
 func (o Box) GetScalarFloat() float64 {
-	Log.Panicf("Box cannot be a Scalar Float")
+	Panicf(DomainError, "Box cannot be a Scalar Float")
 	panic(0)
 }
 
-/*
 func (o Char) GetScalarOrNil() Val {
 	return o
 }
-*/
 func (o Num) GetScalarOrNil() Val {
 	return o
 }
 func (o Mat) GetScalarOrNil() Val {
-	if len(o.M) == 1 {
-		return o.M[0].GetScalarOrNil()
+	if o.Len() == 1 {
+		return o.Vals()[0].GetScalarOrNil()
 	}
 	return nil
 }
@@ -479,26 +473,22 @@ func (o Box) GetScalarOrNil() Val {
 	return o
 }
 
-/*
 func (o Char) Size() int {
 	return 1
 }
-*/
 func (o Num) Size() int {
 	return 1
 }
 func (o Mat) Size() int {
-	return len(o.M)
+	return o.Len()
 }
 func (o Box) Size() int {
 	return 1
 }
 
-/*
 func (o Char) Shape() []int {
 	return nil
 }
-*/
 func (o Num) Shape() []int {
 	return nil
 }
@@ -509,26 +499,22 @@ func (o Box) Shape() []int {
 	return nil
 }
 
-/*
 func (o Char) Ravel() []Val {
 	return []Val{o}
 }
-*/
 func (o Num) Ravel() []Val {
 	return []Val{o}
 }
 func (o Mat) Ravel() []Val {
-	return o.M
+	return o.Vals()
 }
 func (o Box) Ravel() []Val {
 	return []Val{o}
 }
 
-/*
 func (o Char) ValEnum() ValEnum {
 	return CharVal
 }
-*/
 func (o Num) ValEnum() ValEnum {
 	return NumVal
 }
@@ -539,11 +525,15 @@ func (o Box) ValEnum() ValEnum {
 	return BoxVal
 }
 
-/*
 func (a Char) Compare(x Val) int {
-	b, ok := x.(*Char)
-	if !ok {
-		Log.Panicf("Char::Compare to not-a-Char: %#v", x)
+	var b Char
+	switch t := x.(type) {
+	case *Char:
+		b = *t
+	case Char:
+		b = t
+	default:
+		Panicf(DomainError, "Char::Compare to not-a-Char: %#v", x)
 	}
 	switch {
 	case a.R < b.R:
@@ -555,24 +545,30 @@ func (a Char) Compare(x Val) int {
 	}
 	panic("NOT_REACHED")
 }
-*/
+
+// Compare orders complex numbers by real part, then by imaginary part.
 func (a Num) Compare(x Val) int {
-	fa := a.GetScalarFloat()
-	fx := x.GetScalarFloat()
+	if b, ok := asExact(x); ok {
+		return -b.Compare(a)
+	}
+	ca := a.F
+	cx := x.GetScalarCx()
 	switch {
-	case fa < fx:
+	case real(ca) < real(cx):
 		return -1
-	case fa == fx:
-		return 0
-	case fa > fx:
+	case real(ca) > real(cx):
+		return +1
+	case imag(ca) < imag(cx):
+		return -1
+	case imag(ca) > imag(cx):
 		return +1
 	}
-	panic("NOT_REACHED")
+	return 0
 }
 func (a Mat) Compare(x Val) int {
 	b, ok := x.(*Mat)
 	if !ok {
-		Log.Panicf("Mat::Compare to not-a-Mat: %v", x)
+		Panicf(DomainError, "Mat::Compare to not-a-Mat: %v", x)
 	}
 	switch {
 	case len(a.S) < len(b.S):
@@ -590,8 +586,9 @@ func (a Mat) Compare(x Val) int {
 			return +1
 		}
 	}
-	for i := range a.M {
-		cmp := a.M[i].Compare(b.M[i])
+	aVec, bVec := a.Vals(), b.Vals()
+	for i := range aVec {
+		cmp := Compare(aVec[i], bVec[i])
 		if cmp != 0 {
 			return cmp
 		}
@@ -601,19 +598,20 @@ func (a Mat) Compare(x Val) int {
 func (a Box) Compare(x Val) int {
 	b, ok := x.(*Box)
 	if !ok {
-		Log.Panicf("Box::Compare to not-a-Box: %v", x)
+		Panicf(DomainError, "Box::Compare to not-a-Box: %v", x)
 	}
-	aa := reflect.ValueOf(a).Pointer()
-	bb := reflect.ValueOf(b).Pointer()
+	// Boxed values compare by value; anything else by its printed form.
+	av, aok := a.X.(Val)
+	bv, bok := b.X.(Val)
 	switch {
-	case aa < bb:
+	case aok && bok:
+		return Compare(av, bv)
+	case aok:
 		return -1
-	case aa == bb:
-		return 0
-	case aa > bb:
+	case bok:
 		return +1
 	}
-	panic("NOT_REACHED")
+	return strings.Compare(fmt.Sprintf("%T %v", a.X, a.X), fmt.Sprintf("%T %v", b.X, b.X))
 }
 
 func Compare(a, b Val) int {
@@ -628,6 +626,38 @@ func Compare(a, b Val) int {
 	return a.Compare(b)
 }
 
+// StringMat makes a rank-1 matrix of Chars.
+func StringMat(s string) *Mat {
+	var vec []Val
+	for _, r := range s {
+		vec = append(vec, &Char{r})
+	}
+	return &Mat{M: vec, S: []int{len(vec)}}
+}
+
+// GetString returns the text of a char vector (or a single Char),
+// or ok==false if the value is not all chars.
+func GetString(v Val) (s string, ok bool) {
+	var bb bytes.Buffer
+	switch t := v.(type) {
+	case *Char:
+		return string(t.R), true
+	case *Mat:
+		if len(t.S) != 1 {
+			return "", false
+		}
+		for _, e := range t.Vals() {
+			ch, ok := e.(*Char)
+			if !ok {
+				return "", false
+			}
+			bb.WriteRune(ch.R)
+		}
+		return bb.String(), true
+	}
+	return "", false
+}
+
 func Bool2Cx(b bool) complex128 {
 	if b {
 		return 1
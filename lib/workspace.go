@@ -0,0 +1,424 @@
+package livy
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A workspace file is line oriented.  The first line names the format and version.
+// Then each variable is a line `var Name encoding...`
+// and each user definition is a line `def m|d|o name "quoted source"`,
+// for monadic, dyadic, or operator definitions.
+// Version 2 added exact numbers and `def o` operators.
+// Loading checks that the source of each def is only that definition,
+// so a workspace file cannot run code.  A function assigned a lambda inside
+// a call cannot be saved, since its source cannot restore the call's locals.
+//
+// A value is encoded as space-separated words:
+//
+//	n Real Imag                   -- a *Num
+//	x Numerator/Denominator       -- an *Exact, like 7 or -1/3
+//	c Codepoint                   -- a *Char
+//	m Rank Dim... Element...      -- a *Mat, elements are values
+//	b Value                       -- a *Box holding a value
+//	s "quoted"                    -- a *Box holding a string
+const WorkspaceMagic = "livy-apl-workspace"
+const WorkspaceVersion = 2
+const WorkspaceExtension = ".lws"
+
+// WorkspaceFilename adds the default extension, if the name has none.
+func WorkspaceFilename(name string) string {
+	if filepath.Ext(name) == "" {
+		return name + WorkspaceExtension
+	}
+	return name
+}
+
+func (c *Context) SaveWorkspaceFile(name string) error {
+	filename := WorkspaceFilename(name)
+	var bb bytes.Buffer
+	err := c.SaveWorkspace(&bb)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(filename, bb.Bytes(), 0644)
+	if err != nil {
+		return err
+	}
+	c.WorkspaceName = name
+	return nil
+}
+
+func (c *Context) LoadWorkspaceFile(name string) error {
+	r, err := os.Open(WorkspaceFilename(name))
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	err = c.LoadWorkspace(r)
+	if err != nil {
+		return err
+	}
+	c.WorkspaceName = name
+	return nil
+}
+
+func (c *Context) CopyWorkspaceFile(name string, names []string) error {
+	r, err := os.Open(WorkspaceFilename(name))
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return c.CopyWorkspace(r, names)
+}
+
+// SaveWorkspace writes all Globals and user definitions.
+func (c *Context) SaveWorkspace(w io.Writer) error {
+	for key := range c.capturedDefs {
+		if _, ok := c.defsOf(key.dyadic)[key.name]; ok {
+			return fmt.Errorf("cannot save %s: it uses the locals of the function that defined it", key.name)
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "%s %d\n", WorkspaceMagic, WorkspaceVersion)
+
+	var names []string
+	for k := range c.Globals {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		var bb bytes.Buffer
+		err := encodeVal(&bb, c.Globals[k])
+		if err != nil {
+			return fmt.Errorf("cannot save variable %s: %v", k, err)
+		}
+		fmt.Fprintf(bw, "var %s%s\n", k, bb.String())
+	}
+
+	saveDefs := func(valence string, defs map[string]string) {
+		var names []string
+		for k := range defs {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+		for _, k := range names {
+			fmt.Fprintf(bw, "def %s %s %s\n", valence, k, strconv.Quote(defs[k]))
+		}
+	}
+	saveDefs("m", c.MonadicDefs)
+	saveDefs("d", c.DyadicDefs)
+
+	names = nil
+	for k := range c.Operators {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		op := c.Operators[k]
+		for _, def := range []*Def{op.Monadic, op.Dyadic} {
+			if def != nil {
+				fmt.Fprintf(bw, "def o %s %s\n", k, strconv.Quote(def.Source))
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+// LoadWorkspace replaces all variables and user definitions with those in the workspace.
+// It reads into a cleared copy of the Context first, so on error, nothing changes.
+func (c *Context) LoadWorkspace(r io.Reader) error {
+	tmp := c.clearedCopy()
+	if err := tmp.readWorkspace(r, nil); err != nil {
+		return err
+	}
+	c.Clear()
+	c.Globals, c.Monadics, c.Dyadics = tmp.Globals, tmp.Monadics, tmp.Dyadics
+	c.MonadicDefs, c.DyadicDefs, c.Operators = tmp.MonadicDefs, tmp.DyadicDefs, tmp.Operators
+	return nil
+}
+
+// clearedCopy is a Context like this one after Clear, without changing this one.
+func (c *Context) clearedCopy() *Context {
+	tmp := *c
+	tmp.Monadics = copyMonadics(c.Monadics)
+	tmp.Dyadics = copyDyadics(c.Dyadics)
+	tmp.Clear()
+	return &tmp
+}
+
+// CopyWorkspace restores only the named variables and definitions,
+// or everything if names is empty, without clearing the Context first.
+func (c *Context) CopyWorkspace(r io.Reader, names []string) error {
+	want := make(map[string]bool)
+	for _, name := range names {
+		want[name] = true
+	}
+	return c.readWorkspace(r, want)
+}
+
+func (c *Context) readWorkspace(r io.Reader, want map[string]bool) (err error) {
+	defer func() {
+		r := recover()
+		if r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 1<<30)
+	if !sc.Scan() {
+		return errors.New("empty workspace file")
+	}
+	var magic string
+	var version int
+	_, err = fmt.Sscanf(sc.Text(), "%s %d", &magic, &version)
+	if err != nil || magic != WorkspaceMagic {
+		return errors.New("not a livy-apl workspace file")
+	}
+	if version > WorkspaceVersion {
+		return fmt.Errorf("workspace version %d is newer than %d", version, WorkspaceVersion)
+	}
+
+	lineNum := 1
+	for sc.Scan() {
+		lineNum++
+		words := &wordReader{s: sc.Text()}
+		switch words.Next() {
+		case "":
+			continue
+		case "var":
+			name := words.Next()
+			if len(want) > 0 && !want[name] {
+				continue
+			}
+			val, err := c.decodeVal(words)
+			if err != nil {
+				return fmt.Errorf("workspace line %d: variable %s: %v", lineNum, name, err)
+			}
+			c.Globals[name] = val
+		case "def":
+			valence := words.Next()
+			name := words.Next()
+			if len(want) > 0 && !want[name] {
+				continue
+			}
+			source, err := strconv.Unquote(words.Next())
+			if err != nil {
+				return fmt.Errorf("workspace line %d: def %s: %v", lineNum, name, err)
+			}
+			def, err := c.parseWorkspaceDef(valence, name, source)
+			if err != nil {
+				return fmt.Errorf("workspace line %d: def %s: %v", lineNum, name, err)
+			}
+			def.Eval(c)
+		default:
+			return fmt.Errorf("workspace line %d: unknown entry", lineNum)
+		}
+	}
+	return sc.Err()
+}
+
+// parseWorkspaceDef parses the saved source of a definition, which must be
+// just a def or function assignment of the name, with the valence given,
+// so that loading a workspace defines things, but never runs other code.
+func (c *Context) parseWorkspaceDef(valence, name, source string) (Expression, error) {
+	lex := Tokenize(source)
+	p := &Parser{Context: c}
+	seq, i := p.ParseSeq(lex, 0)
+	if lex.Tokens[i].Type != EndToken || len(seq.Vec) != 1 {
+		return nil, errors.New("source is not a single definition")
+	}
+	switch x := seq.Vec[0].(type) {
+	case *Def:
+		v := "m"
+		if x.LeftOperand != "" {
+			v = "o"
+		} else if x.Lhs != "" {
+			v = "d"
+		}
+		if x.Name != name || v != valence {
+			return nil, fmt.Errorf("source defines %s %s", v, x.Name)
+		}
+	case *FuncAssign:
+		if x.Name != name || valence == "o" {
+			return nil, fmt.Errorf("source assigns function %s", x.Name)
+		}
+		if !onlyLiteralOperands(x.Fn) {
+			return nil, errors.New("source computes an operand")
+		}
+	default:
+		return nil, errors.New("source is not a definition")
+	}
+	return seq.Vec[0], nil
+}
+
+// onlyLiteralOperands tells if evaluating the function expression runs no code,
+// because the value operands of its operators are all literals.
+func onlyLiteralOperands(fn FuncExpression) bool {
+	switch x := fn.(type) {
+	case *Derived:
+		return onlyLiteralOperands(x.Left) && (x.Right == nil || onlyLiteralOperands(x.Right))
+	case *OperatorCall:
+		if !onlyLiteralOperands(x.Left) || (x.Right != nil && !onlyLiteralOperands(x.Right)) {
+			return false
+		}
+		switch x.RightVal.(type) {
+		case nil, *Number, *Literal:
+			return true
+		}
+		return false
+	}
+	return true
+}
+
+func encodeVal(bb *bytes.Buffer, v Val) error {
+	switch t := v.(type) {
+	case *Num:
+		fmt.Fprintf(bb, " n %s %s", strconv.FormatFloat(real(t.F), 'g', -1, 64), strconv.FormatFloat(imag(t.F), 'g', -1, 64))
+	case *Exact:
+		fmt.Fprintf(bb, " x %s", t.Rat().RatString())
+	case *Char:
+		fmt.Fprintf(bb, " c %d", t.R)
+	case *Mat:
+		fmt.Fprintf(bb, " m %d", len(t.S))
+		for _, d := range t.S {
+			fmt.Fprintf(bb, " %d", d)
+		}
+		for _, e := range t.Vals() {
+			err := encodeVal(bb, e)
+			if err != nil {
+				return err
+			}
+		}
+	case *Box:
+		switch x := t.X.(type) {
+		case Val:
+			bb.WriteString(" b")
+			return encodeVal(bb, x)
+		case string:
+			fmt.Fprintf(bb, " s %s", strconv.Quote(x))
+		default:
+			return fmt.Errorf("cannot save Box of %T", t.X)
+		}
+	default:
+		return fmt.Errorf("cannot save value of type %T", v)
+	}
+	return nil
+}
+
+func (c *Context) decodeVal(words *wordReader) (Val, error) {
+	switch w := words.Next(); w {
+	case "n":
+		re, err := strconv.ParseFloat(words.Next(), 64)
+		if err != nil {
+			return nil, err
+		}
+		im, err := strconv.ParseFloat(words.Next(), 64)
+		if err != nil {
+			return nil, err
+		}
+		return &Num{complex(re, im)}, nil
+	case "x":
+		text := words.Next()
+		r, ok := new(big.Rat).SetString(text)
+		if !ok {
+			return nil, fmt.Errorf("bad exact number %q", text)
+		}
+		return ExactRat(r), nil
+	case "c":
+		r, err := strconv.Atoi(words.Next())
+		if err != nil {
+			return nil, err
+		}
+		return &Char{rune(r)}, nil
+	case "m":
+		rank, err := strconv.Atoi(words.Next())
+		if err != nil {
+			return nil, err
+		}
+		// Each dimension takes at least 2 more bytes of the line, and each element 4,
+		// so a bad rank or shape cannot make a huge array.
+		if rank < 0 || rank > words.Len()/2 {
+			return nil, fmt.Errorf("bad rank %d", rank)
+		}
+		shape := make([]int, rank)
+		n := 1
+		for i := range shape {
+			shape[i], err = strconv.Atoi(words.Next())
+			if err != nil {
+				return nil, err
+			}
+			if shape[i] < 0 || (shape[i] > 0 && n > words.Len()/4/shape[i]) {
+				return nil, fmt.Errorf("bad shape %v", shape[:i+1])
+			}
+			n *= shape[i]
+		}
+		c.Alloc(n)
+		vec := make([]Val, n)
+		for i := range vec {
+			vec[i], err = c.decodeVal(words)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return &Mat{M: vec, S: shape}, nil
+	case "b":
+		x, err := c.decodeVal(words)
+		if err != nil {
+			return nil, err
+		}
+		return &Box{x}, nil
+	case "s":
+		s, err := strconv.Unquote(words.Next())
+		if err != nil {
+			return nil, err
+		}
+		return &Box{s}, nil
+	case "":
+		return nil, errors.New("truncated value")
+	default:
+		return nil, fmt.Errorf("bad value code %q", w)
+	}
+}
+
+// wordReader splits a line into words at spaces, keeping quoted strings whole.
+type wordReader struct {
+	s string
+}
+
+// Len is the number of bytes left in the line.
+func (o *wordReader) Len() int {
+	return len(o.s)
+}
+
+func (o *wordReader) Next() string {
+	o.s = strings.TrimLeft(o.s, " ")
+	if o.s == "" {
+		return ""
+	}
+	if o.s[0] == '"' {
+		q, err := strconv.QuotedPrefix(o.s)
+		if err == nil {
+			o.s = o.s[len(q):]
+			return q
+		}
+	}
+	i := strings.IndexByte(o.s, ' ')
+	if i < 0 {
+		i = len(o.s)
+	}
+	w := o.s[:i]
+	o.s = o.s[i:]
+	return w
+}
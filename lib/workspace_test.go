@@ -0,0 +1,142 @@
+package livy
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func evalIn(c *Context, src string) Val {
+	lex := Tokenize(src)
+	p := &Parser{Context: c}
+	expr, _ := p.ParseSeq(lex, 0)
+	return expr.Eval(c)
+}
+
+func TestSaveLoadWorkspace(t *testing.T) {
+	c := NewContext()
+	evalIn(c, `A = 2 3 rho 1.5 -2 4+j3 ; B = box iota 4 ; C = (box 7) (box 1 2)`)
+	evalIn(c, `def twice X { X * 2 } ; def X plus Y ; T { T = X ; T + Y }`)
+
+	var bb bytes.Buffer
+	if err := c.SaveWorkspace(&bb); err != nil {
+		t.Fatalf("SaveWorkspace: %v", err)
+	}
+
+	d := NewContext()
+	if err := d.LoadWorkspace(bytes.NewReader(bb.Bytes())); err != nil {
+		t.Fatalf("LoadWorkspace: %v", err)
+	}
+	for _, name := range []string{"A", "B", "C"} {
+		if got, want := d.Globals[name].String(), c.Globals[name].String(); got != want {
+			t.Errorf("Variable %s: got %q want %q", name, got, want)
+		}
+	}
+	if got, want := evalIn(d, `twice 10 plus A`).String(), `[2 3 ]{23 16 28+j6 23 16 28+j6 } `; got != want {
+		t.Errorf("Got %q want %q", got, want)
+	}
+
+	e := NewContext()
+	if err := e.CopyWorkspace(bytes.NewReader(bb.Bytes()), []string{"B", "twice"}); err != nil {
+		t.Fatalf("CopyWorkspace: %v", err)
+	}
+	if _, ok := e.Globals["A"]; ok {
+		t.Errorf("CopyWorkspace copied A, but was not asked to")
+	}
+	if got, want := evalIn(e, `twice unbox B`).String(), `[4 ]{0 2 4 6 } `; got != want {
+		t.Errorf("Got %q want %q", got, want)
+	}
+}
+
+func TestLoadBadWorkspace(t *testing.T) {
+	c := NewContext()
+	evalIn(c, `A = 1 2 3 ; def twice X { X * 2 }`)
+	for _, ws := range []string{
+		"",
+		"not a workspace\n",
+		"livy-apl-workspace 99\n",
+		"livy-apl-workspace 2\nvar B n 1 0\nvar C m 1 3 n 1 0 n 2 0\n",
+		"livy-apl-workspace 2\nvar B m 2 1000000000 1000000000 n 0 0\n",
+		"livy-apl-workspace 2\nvar B m 99999999999 n 0 0\n",
+		"livy-apl-workspace 2\nvar B m -1\n",
+		"livy-apl-workspace 2\nvar B m 1 -2\n",
+		"livy-apl-workspace 2\nvar B x 1/0\n",
+		"livy-apl-workspace 2\ndef m thrice \"def thrice X { X * 3 }\"\nbogus\n",
+	} {
+		if err := c.LoadWorkspace(strings.NewReader(ws)); err == nil {
+			t.Errorf("Loading %q should fail", ws)
+		}
+		if _, ok := c.Globals["B"]; ok {
+			t.Errorf("Failing to load %q set B", ws)
+		}
+		if got := evalIn(c, `twice A`).String(); got != "[3 ]{2 4 6 } " {
+			t.Errorf("After failing to load %q, got %q", ws, got)
+		}
+		if _, ok := c.Monadics["thrice"]; ok {
+			t.Errorf("Failing to load %q defined thrice", ws)
+		}
+	}
+
+	// A version 1 workspace, from before exact numbers, still loads.
+	if err := c.LoadWorkspace(strings.NewReader("livy-apl-workspace 1\nvar B n 5 0\n")); err != nil {
+		t.Fatalf("Loading version 1: %v", err)
+	}
+	if _, ok := c.Globals["A"]; ok {
+		t.Errorf("Loading a workspace kept A")
+	}
+	if _, ok := c.Monadics["twice"]; ok {
+		t.Errorf("Loading a workspace kept twice")
+	}
+}
+
+// Loading only defines things; a def line that would run other code is refused.
+func TestLoadWorkspaceRunsNoCode(t *testing.T) {
+	for _, ws := range []string{
+		"def m foo \"A = 1 2 3\"\n",
+		"def m foo \"def foo Y { Y } ; A = 1 2 3\"\n",
+		"def m foo \"def bar Y { Y }\"\n",
+		"def d foo \"def foo Y { Y }\"\n",
+		"def m foo \"def X foo Y { Y }\"\n",
+		"def o twice \"def (f twice) Y { f f Y }\"\ndef m foo \"foo = - twice\"\ndef m bar \"bar = (A = 5) + 1\"\n",
+		"def o pow \"def (f pow N) Y { Y }\"\ndef m foo \"foo = - pow (A = 5)\"\n",
+	} {
+		c := NewContext()
+		if err := c.LoadWorkspace(strings.NewReader("livy-apl-workspace 2\n" + ws)); err == nil {
+			t.Errorf("Loading %q should fail", ws)
+		}
+		if _, ok := c.Globals["A"]; ok {
+			t.Errorf("Loading %q assigned A", ws)
+		}
+	}
+
+	c := NewContext()
+	ws := "livy-apl-workspace 2\n" +
+		"def o pow \"def (f pow N) Y { f Y + N }\"\n" +
+		"def m inc \"inc = - pow 1\"\n" +
+		"def m sum \"sum = { +/ Y }\"\n"
+	if err := c.LoadWorkspace(strings.NewReader(ws)); err != nil {
+		t.Fatalf("LoadWorkspace: %v", err)
+	}
+	if got := evalIn(c, `inc sum 1 2 3`).String(); got != "-7 " {
+		t.Errorf("Got %q, wanted -7", got)
+	}
+}
+
+// A lambda that sees the locals of the function that assigned it cannot be saved as source.
+func TestSaveCapturedLambda(t *testing.T) {
+	c := NewContext()
+	evalIn(c, `def mk K { g = { X + K } ; 0 } ; mk 5`)
+	if got := evalIn(c, `1 g 2`).String(); got != "6 " {
+		t.Errorf("Got %q, wanted 6", got)
+	}
+	var bb bytes.Buffer
+	if err := c.SaveWorkspace(&bb); err == nil || !strings.Contains(err.Error(), "cannot save g") {
+		t.Errorf("Got %v, wanted an error about g", err)
+	}
+
+	// Defined again at top level, it can be.
+	evalIn(c, `K = 5 ; g = { X + K }`)
+	if err := c.SaveWorkspace(&bb); err != nil {
+		t.Errorf("SaveWorkspace: %v", err)
+	}
+}